@@ -0,0 +1,111 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// purposes of one-time tokens (a token is only valid for its purpose)
+const (
+	PurposePasswordReset     = "pwreset"
+	PurposeEMailVerification = "verify"
+	PurposeLoginChallenge    = "login2fa" // password was correct, two-factor code is pending
+)
+
+// lifetimes of one-time tokens
+const (
+	PasswordResetTTL     = 1 * time.Hour
+	EMailVerificationTTL = 48 * time.Hour
+	LoginChallengeTTL    = 5 * time.Minute
+)
+
+// custom error types
+var (
+	ErrInvalidToken = errors.New("invalid or expired token") // unknown, used or expired
+)
+
+// only the hash of a token is stored, so the registry doesn't contain usable tokens
+func oneTimeKey(purpose string, token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return "ott_" + purpose + "_" + hex.EncodeToString(hash[:])
+}
+
+// CreateOneTimeToken issues a random token for a user which can be used once within the given time
+// eg. the link of a password reset mail
+func CreateOneTimeToken(purpose string, userID string, ttl time.Duration) (string, error) {
+
+	var ctx = context.Background()
+
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	err = client.Set(ctx, oneTimeKey(purpose, token), userID, ttl).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// PeekOneTimeToken returns the user of a token without using it up (eg. to validate a request first)
+func PeekOneTimeToken(purpose string, token string) (string, error) {
+
+	var ctx = context.Background()
+
+	if token == "" {
+		return "", ErrInvalidToken
+	}
+
+	userID, err := client.Get(ctx, oneTimeKey(purpose, token)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// ConsumeOneTimeToken returns the user of a token and removes it
+// read and delete are done in a transaction, so a token can't be used twice by concurrent requests
+func ConsumeOneTimeToken(purpose string, token string) (string, error) {
+
+	var ctx = context.Background()
+
+	if token == "" {
+		return "", ErrInvalidToken
+	}
+
+	key := oneTimeKey(purpose, token)
+
+	var get *redis.StringCmd
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	userID, err := get.Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,269 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/twinj/uuid"
+)
+
+// MaxSessions is the number of devices a user can be logged in with at the same time
+// the oldest session is revoked by the next login
+const MaxSessions = 10
+
+// custom error types
+var (
+	ErrSessionNotFound = errors.New("session not found") // revoked, expired or someone else's
+)
+
+// Session is a device (browser) a user is logged in with
+type Session struct {
+	ID          string    `json:"id"`
+	UserAgent   string    `json:"userAgent"`
+	IP          string    `json:"ip"`
+	CreatedTS   time.Time `json:"createdTS"`
+	RefreshedTS time.Time `json:"refreshedTS"`
+	Current     bool      `json:"current"` // the session of the request (not stored)
+}
+
+// sessionRecord is stored in the registry (redis), including the current tokens of the session
+type sessionRecord struct {
+	Session
+	UserID      string `json:"userID"`
+	AccessUUID  string `json:"accessUUID"`
+	RefreshUUID string `json:"refreshUUID"`
+}
+
+// registry keys: a record per session, a set of session IDs per user
+func sessionKey(sessionID string) string   { return "sess_" + sessionID }
+func userSessionsKey(userID string) string { return "sessions_" + userID }
+
+// CreateSession registers a new session at log-in and sends its token pair via cookie
+func CreateSession(c *gin.Context, userID string, ip string) error {
+
+	var ctx = context.Background()
+
+	// make room for the new session
+	sessions, err := readSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(sessions) >= MaxSessions {
+		// oldest last (see readSessions)
+		for _, s := range sessions[MaxSessions-1:] {
+			err = deleteSession(ctx, s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	record := sessionRecord{
+		Session: Session{
+			ID:          uuid.NewV4().String(),
+			UserAgent:   c.Request.UserAgent(),
+			IP:          ip,
+			CreatedTS:   now,
+			RefreshedTS: now,
+		},
+		UserID: userID,
+	}
+
+	td, err := CreateTokens(c, userID, record.ID)
+	if err != nil {
+		return err
+	}
+
+	return saveSession(ctx, &record, td)
+}
+
+// RefreshSession issues a new token pair for the session of a (valid) refresh token
+// the previous tokens of the session are removed
+func RefreshSession(c *gin.Context, au *AccessDetails, ip string) error {
+
+	var ctx = context.Background()
+
+	record, err := readSession(ctx, au.SessionID)
+	if err != nil {
+		return err
+	}
+
+	// only the session's current refresh token is accepted (see CheckRefreshToken)
+	if record.UserID != au.UserID || record.RefreshUUID != au.TokenUUID {
+		return ErrSessionNotFound
+	}
+
+	// rotation: the family of the previous refresh token is kept
+	err = client.Del(ctx, record.AccessUUID, record.RefreshUUID).Err()
+	if err != nil {
+		return err
+	}
+
+	td, err := CreateTokens(c, record.UserID, record.ID)
+	if err != nil {
+		return err
+	}
+
+	record.RefreshedTS = time.Now()
+	record.IP = ip
+	record.UserAgent = c.Request.UserAgent()
+
+	return saveSession(ctx, record, td)
+}
+
+// ListSessions returns the active sessions of a user (most recently used first)
+func ListSessions(userID string, currentSessionID string) ([]Session, error) {
+
+	var ctx = context.Background()
+
+	records, err := readSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(records))
+	for i, r := range records {
+		sessions[i] = r.Session
+		sessions[i].Current = r.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs a user out of a session (device)
+func RevokeSession(userID string, sessionID string) error {
+
+	var ctx = context.Background()
+
+	record, err := readSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	// other users' sessions are not found
+	if record.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	return deleteSession(ctx, record)
+}
+
+// RevokeSessions logs a user out of all sessions except the given one (empty = all sessions)
+func RevokeSessions(userID string, exceptSessionID string) error {
+
+	var ctx = context.Background()
+
+	records, err := readSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if r.ID == exceptSessionID {
+			continue
+		}
+		err = deleteSession(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writes the session record with its new tokens, the session lives as long as its refresh token
+func saveSession(ctx context.Context, record *sessionRecord, td *TokenDetails) error {
+
+	record.AccessUUID = td.AccessUUID
+	record.RefreshUUID = td.RefreshUUID
+	record.Current = false
+
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(time.Unix(td.RtExpires, 0))
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(record.ID), value, ttl)
+		pipe.SAdd(ctx, userSessionsKey(record.UserID), record.ID)
+		// the set expires with the user's most recent session
+		pipe.Expire(ctx, userSessionsKey(record.UserID), ttl)
+		return nil
+	})
+
+	return err
+}
+
+// reads a single session record
+func readSession(ctx context.Context, sessionID string) (*sessionRecord, error) {
+
+	if sessionID == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	value, err := client.Get(ctx, sessionKey(sessionID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	var record sessionRecord
+	err = json.Unmarshal(value, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// reads the session records of a user (most recently used first)
+// expired sessions are removed from the user's set
+func readSessions(ctx context.Context, userID string) ([]*sessionRecord, error) {
+
+	ids, err := client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*sessionRecord
+	for _, id := range ids {
+		record, err := readSession(ctx, id)
+		if err != nil {
+			if err == ErrSessionNotFound {
+				client.SRem(ctx, userSessionsKey(userID), id)
+				continue
+			}
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].RefreshedTS.After(records[j].RefreshedTS)
+	})
+
+	return records, nil
+}
+
+// removes a session and its tokens
+// rotated refresh tokens keep their family until they expire, so their reuse is still logged
+func deleteSession(ctx context.Context, record *sessionRecord) error {
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, record.AccessUUID, record.RefreshUUID, familyKey(record.RefreshUUID), sessionKey(record.ID))
+		pipe.SRem(ctx, userSessionsKey(record.UserID), record.ID)
+		return nil
+	})
+
+	return err
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"forza-garage/helpers"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/twinj/uuid"
 )
 
@@ -25,6 +27,7 @@ const (
 var (
 	ErrUnauthorized = errors.New("unauthorized") // invalid token/cookie
 	ErrNotLoggedIn  = errors.New("requires authorization")
+	ErrTokenReused  = errors.New("refresh token was already used")
 )
 
 // TokenDetails enthält die Daten von AT und RT
@@ -35,27 +38,29 @@ type TokenDetails struct {
 	RefreshUUID  string
 	AtExpires    int64
 	RtExpires    int64
+	FamilyID     string // refresh tokens of the same log-in (session), see CheckRefreshToken
 }
 
 // AccessDetails Token Metadata für die Registry (Key/Value redis)
 type AccessDetails struct {
 	TokenUUID string
 	UserID    string
+	SessionID string
 }
 
-// CreateTokens erzeugt ein Token-Paar, regstriert es in Redis und sendet es via Cookie
-func CreateTokens(c *gin.Context, userID string) error {
+// CreateTokens erzeugt ein Token-Paar für eine Session, regstriert es in Redis und sendet es via Cookie
+func CreateTokens(c *gin.Context, userID string, sessionID string) (*TokenDetails, error) {
 
 	// Create pair of AT & RT
-	ts, err := CreateToken(userID)
+	ts, err := CreateToken(userID, sessionID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Register Tokens
 	err = CreateAuth(userID, ts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Tokens für "Versendung" aufbereiten
@@ -67,10 +72,10 @@ func CreateTokens(c *gin.Context, userID string) error {
 	// Send token pair to client as a server-side cookie
 	err = helpers.SetCookie(c, os.Getenv("JWTCK_NAME"), tokens)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return ts, nil
 }
 
 // Authenticate prüft die Berechtigung zur Ausführung einer Route
@@ -90,114 +95,15 @@ func Authenticate(r *http.Request) (string, error) {
 	return userID, nil
 }
 
-// DeleteAuths zählt die Tokens eines Benutzers. Wenn zu viele da sind (siehe Limit im Code)
-// werden aus Sicherheitsgründen alle gelöscht; sonst nur das aktuelle/alte. Die Funktion
-// wird üblicherweise für RTs benutzt, ist aber generisch gehalten
-// Grundidee:
-// https://valor-software.com/articles/json-web-token-authorization-with-access-and-refresh-tokens-in-angular-application-with-node-js-server.html
-// alternativ können auch alle RTs gelöscht werden (param ggf. entfernen) und alle ATs falls >= x RTs vorhanden
-// könnte halt mehr Last auslösen
-func DeleteAuths(tokenType string, userID string, currentUUID string) (int64, error) {
-
-	// redis is case-sensitive
-	// tt := strings.ToLower(tokenType)
-	tt := ""
-	switch tokenType {
-	case AT:
-		tt = "at"
-	case RT:
-		tt = "rt"
-	}
-	searchString := tt + "_*"
-
-	// fmt.Println("looking for ", searchString)
-
-	var cursor uint64
-	var allKeys []string // alle keys für den Token Type
-	var usrKeys []string // alle keys für den gesuchten User
-
-	var keys []string // gelesene keys für die cursor iteration
-	var err error
-
-	var ctx = context.Background()
-
-	// in redis können nur keys durchsucht werden, nicht die values.
-	// daher müssen zuerst alle keys für den User gelesen werden und dann werden deren
-	// Inhalte client-seitig geprüft
-	for {
-		keys, cursor, err = client.Scan(ctx, cursor, searchString, 10).Result()
-		if err != nil {
-			return 0, err
-		}
-
-		// jede cursor iteration in die gesamtliste übernehmen
-		allKeys = append(allKeys, keys...)
-
-		/*
-			// spread operator entspricht dem hier
-			for _, v := range keys {
-				allKeys = append(allKeys, v)
-			}
-		*/
-
-		if cursor == 0 {
-			break
-		}
-	}
-
-	// fmt.Printf("found %d keys\n", len(allKeys))
-
-	// values auslesen
-	for _, v := range allKeys {
-		//fmt.Println(i, v)
-
-		val, err := client.Get(ctx, v).Result()
-		if err != nil {
-			return 0, err // panic, abbrechen
-		}
-
-		if val == userID {
-			usrKeys = append(usrKeys, v)
-		}
-	}
-
-	// fmt.Printf("found %d for user\n", len(usrKeys))
-
-	// delete all if there are X or more (r) tokens currently in use
-	var delErr error // preserve last error that may have occured while in the loop
-	var delCnt int64 = 0
-	if len(usrKeys) >= 5 {
-		// fmt.Println("deleting many auths")
-		for _, v := range usrKeys {
-			deleted, err := client.Del(ctx, v).Result()
-			if err != nil {
-				delErr = err
-				delCnt = deleted
-			}
-			delCnt += deleted
-		}
-	} else {
-		// sonst nur das aktuelle (r) token löschen
-		if len(usrKeys) >= 1 {
-			// fmt.Println("deleting current auth")
-			delCnt, delErr = client.Del(ctx, currentUUID).Result()
-		}
-	}
-
-	// in case of errors report 0 removals
-	if delErr != nil {
-		delCnt = 0
-	}
-
-	return delCnt, delErr
-}
-
 // CreateToken erzeugt ein Token-Paar (AT & RT)
-func CreateToken(userID string) (*TokenDetails, error) {
+func CreateToken(userID string, sessionID string) (*TokenDetails, error) {
 
 	var err error
 	td := &TokenDetails{}
 
+	// every refresh token of a session belongs to the same family
+	td.FamilyID = sessionID
+
 	// access token
 	td.AtExpires = time.Now().Add(time.Minute * 15).Unix() // default 15 min
 	// td.AtExpires = time.Now().Add(time.Minute * 5).Unix() // test 5 min
@@ -213,6 +119,7 @@ func CreateToken(userID string) (*TokenDetails, error) {
 	atClaims["authorized"] = true
 	atClaims["access_uuid"] = td.AccessUUID
 	atClaims["user_id"] = userID // userID rather than username (login name)
+	atClaims["session_id"] = sessionID
 	atClaims["exp"] = td.AtExpires
 	// weitere props analog https://github.com/omsec/racing-api/blob/master/login.php möglich
 
@@ -226,6 +133,7 @@ func CreateToken(userID string) (*TokenDetails, error) {
 	rtClaims := jwt.MapClaims{}
 	rtClaims["refresh_uuid"] = td.RefreshUUID
 	rtClaims["user_id"] = userID
+	rtClaims["session_id"] = sessionID
 	rtClaims["exp"] = td.RtExpires
 	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
 	td.RefreshToken, err = rt.SignedString([]byte(os.Getenv("REFRESH_SECRET")))
@@ -259,9 +167,53 @@ func CreateAuth(userID string, td *TokenDetails) error {
 		return err
 	}
 
+	// the family is kept after the token was rotated (deleted) to discover its reuse
+	err = client.Set(ctx, familyKey(td.RefreshUUID), td.FamilyID, rt.Sub(now)).Err()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// registry key of a refresh token's family
+func familyKey(refreshUUID string) string { return "rtf_" + refreshUUID }
+
+// CheckRefreshToken reads the userID of a refresh token which must be the current one of its family
+// a rotated token is presented if it was stolen (or the client misbehaves), hence the whole
+// family (session) is revoked - the legitimate client needs to log-in again
+func CheckRefreshToken(au *AccessDetails) (string, error) {
+
+	var ctx = context.Background()
+
+	userID, err := client.Get(ctx, au.TokenUUID).Result()
+	if err == nil {
+		return userID, nil
+	}
+	if err != redis.Nil {
+		return "", err
+	}
+
+	familyID, err := client.Get(ctx, familyKey(au.TokenUUID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			// expired or logged-out
+			return "", ErrUnauthorized
+		}
+		return "", err
+	}
+
+	// security event
+	log.Printf("security: reuse of rotated refresh token %s (user %s, session %s), session revoked", au.TokenUUID, au.UserID, familyID)
+
+	err = RevokeSession(au.UserID, familyID)
+	if err != nil && err != ErrSessionNotFound {
+		return "", err
+	}
+
+	return "", ErrTokenReused
+}
+
 // ExtractToken liefert ein noch verschlüsseltes Token
 func ExtractToken(tokenType string, r *http.Request) (string, error) {
 
@@ -352,9 +304,12 @@ func ExtractTokenMetadata(tokenType string, r *http.Request) (*AccessDetails, er
 		if !ok {
 			return nil, err
 		}
+		// tokens issued before sessions were introduced don't have one
+		sessionID, _ := claims["session_id"].(string)
 		return &AccessDetails{
 			TokenUUID: accessUUID,
 			UserID:    userID,
+			SessionID: sessionID,
 		}, nil
 	}
 	return nil, err
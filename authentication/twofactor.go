@@ -0,0 +1,69 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MaxTwoFactorFailures is the number of wrong two-factor codes after which a user can't log-in for a while
+// it's counted per user (not per log-in challenge), so logging-in again doesn't allow more guesses
+const MaxTwoFactorFailures = 5
+
+// TwoFactorLockTTL is the time the failures are kept (extended by every wrong code)
+const TwoFactorLockTTL = 15 * time.Minute
+
+// custom error types
+var (
+	ErrTooManyFailures = errors.New("too many wrong codes") // wait for TwoFactorLockTTL
+)
+
+func twoFactorFailuresKey(userID string) string {
+	return "2fa_failures_" + userID
+}
+
+// CheckTwoFactorFailures returns ErrTooManyFailures if a user has entered too many wrong codes recently
+func CheckTwoFactorFailures(userID string) error {
+
+	var ctx = context.Background()
+
+	failures, err := client.Get(ctx, twoFactorFailuresKey(userID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return nil
+		}
+		return err
+	}
+
+	if failures >= MaxTwoFactorFailures {
+		return ErrTooManyFailures
+	}
+
+	return nil
+}
+
+// RecordTwoFactorFailure counts a wrong code of a user
+func RecordTwoFactorFailure(userID string) error {
+
+	var ctx = context.Background()
+
+	key := twoFactorFailuresKey(userID)
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, TwoFactorLockTTL)
+		return nil
+	})
+
+	return err
+}
+
+// ResetTwoFactorFailures removes the failures of a user after a successful log-in
+func ResetTwoFactorFailures(userID string) error {
+
+	var ctx = context.Background()
+
+	return client.Del(ctx, twoFactorFailuresKey(userID)).Err()
+}
@@ -0,0 +1,174 @@
+package authentication
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestTwoFactorFailuresPerUser(t *testing.T) {
+	useFakeRedis(t)
+
+	user := "5fd2bb5a67e8b0c5f4d3e2a1"
+	other := "5fd2bb5a67e8b0c5f4d3e2a2"
+
+	_, err := CreateOneTimeToken(PurposeLoginChallenge, user, LoginChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxTwoFactorFailures-1; i++ {
+		if err = RecordTwoFactorFailure(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = CheckTwoFactorFailures(user); err != nil {
+		t.Fatalf("after %d failures: err = %v, want nil", MaxTwoFactorFailures-1, err)
+	}
+
+	// logging-in again doesn't reset the count
+	_, err = CreateOneTimeToken(PurposeLoginChallenge, user, LoginChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = RecordTwoFactorFailure(user); err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckTwoFactorFailures(user); err != ErrTooManyFailures {
+		t.Errorf("after a new challenge: err = %v, want %v", err, ErrTooManyFailures)
+	}
+
+	if err = CheckTwoFactorFailures(other); err != nil {
+		t.Errorf("other user: err = %v, want nil", err)
+	}
+
+	if err = ResetTwoFactorFailures(user); err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckTwoFactorFailures(user); err != nil {
+		t.Errorf("after reset: err = %v, want nil", err)
+	}
+}
+
+// useFakeRedis connects the package to an in-memory store
+// it understands the few commands used by this package (expiry is ignored)
+func useFakeRedis(t *testing.T) {
+	data := make(map[string]string)
+
+	client = redis.NewClient(&redis.Options{
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			server, conn := net.Pipe()
+			go serveFakeRedis(server, data)
+			return conn, nil
+		},
+	})
+	t.Cleanup(func() { client.Close() })
+}
+
+func serveFakeRedis(conn net.Conn, data map[string]string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	var queued []string // replies of a transaction (MULTI/EXEC)
+	inTx := false
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch cmd := strings.ToLower(args[0]); {
+		case cmd == "multi":
+			inTx, queued = true, nil
+			reply = "+OK\r\n"
+		case cmd == "exec":
+			reply = fmt.Sprintf("*%d\r\n%s", len(queued), strings.Join(queued, ""))
+			inTx = false
+		case inTx:
+			queued = append(queued, execute(args, data))
+			reply = "+QUEUED\r\n"
+		default:
+			reply = execute(args, data)
+		}
+
+		if _, err = conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func execute(args []string, data map[string]string) string {
+	switch strings.ToLower(args[0]) {
+	case "ping":
+		return "+PONG\r\n"
+	case "set":
+		data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "get":
+		value, ok := data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "incr":
+		n, _ := strconv.Atoi(data[args[1]])
+		n++
+		data[args[1]] = strconv.Itoa(n)
+		return fmt.Sprintf(":%d\r\n", n)
+	case "expire", "pexpire":
+		_, ok := data[args[1]]
+		if !ok {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	case "del":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := data[key]; ok {
+				delete(data, key)
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+// reads an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2) // \r\n
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
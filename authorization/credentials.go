@@ -23,6 +23,8 @@ type Credentials struct {
 	RoleCode     int32 `bson:"roleCD"`
 	LanguageCode int32 `bson:"languageCD"` // ToDo: Lesen aus Header für ANONYM, DB für Members (override-Möglichkeit)
 	Friends      []UserRef
+	friendsRead  bool // the full friendlist was loaded
+	lookupFriend bool // IsFriend queries the database if the friendlist wasn't loaded (see LookupFriendship)
 	userCol      *mongo.Collection
 	socialCol    *mongo.Collection
 }
@@ -49,6 +51,12 @@ func (c *Credentials) SetConnections(mongoCollections map[string]*mongo.Collecti
 func (c *Credentials) GetCredentials(userOID primitive.ObjectID, loadFriendlist bool) *Credentials {
 	var credentials Credentials
 
+	// anonymous visitor, no need to access the database
+	if userOID == primitive.NilObjectID {
+		c.setDefaultProfile(&credentials)
+		return &credentials
+	}
+
 	fields := bson.D{
 		{Key: "_id", Value: 0}, // _id kommt immer, ausser es wird explizit ausgeschlossen (0)
 		{Key: "loginName", Value: 1},
@@ -68,10 +76,13 @@ func (c *Credentials) GetCredentials(userOID primitive.ObjectID, loadFriendlist
 		c.setDefaultProfile(&credentials)
 	}
 	credentials.UserID = userOID // not read again from DB ;-)
+	credentials.socialCol = c.socialCol
 
 	// friendlist ist referenced from its own collection, add it
+	// it's required by list queries (VisibilityFilter), single items may be checked by LookupFriendship
 	if loadFriendlist {
 		credentials.Friends, _ = c.getReferences(userOID, "friend")
+		credentials.friendsRead = true
 		// error checking removed, since the user is already checked, even in case of an error
 		/*
 			if err != nil {
@@ -86,6 +97,13 @@ func (c *Credentials) GetCredentials(userOID primitive.ObjectID, loadFriendlist
 	return &credentials
 }
 
+// LookupFriendship lets IsFriend query single friendships instead of loading the whole friendlist
+// only for read access - friends may see items shared with them, but not change them
+func (c *Credentials) LookupFriendship() *Credentials {
+	c.lookupFriend = c.socialCol != nil
+	return c
+}
+
 // this is used as the error handler of GetCredentials
 // any error of that function will be threated as an anonymous user, receiving the default credentials
 func (c *Credentials) setDefaultProfile(credentials *Credentials) {
@@ -110,8 +128,8 @@ func (c *Credentials) getReferences(userOID primitive.ObjectID, relationType str
 		"userName": 1,
 	}
 
-	// ToDo: Limit sinnvoll?
-	opts := options.Find().SetProjection(fields).SetLimit(20).SetSort(dbSort)
+	// not limited, permissions depend on the complete list
+	opts := options.Find().SetProjection(fields).SetSort(dbSort)
 
 	// different query depending on relation type
 	var filter bson.M
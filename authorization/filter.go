@@ -0,0 +1,87 @@
+package authorization
+
+import (
+	"forza-garage/lookups"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FilterBuilder assembles the filter document of list queries
+// fields are compared for equality (or with an operator document), conditions such as $or are
+// combined by $and - so no key is ever repeated within the same document
+type FilterBuilder struct {
+	fields     bson.D
+	conditions bson.A
+}
+
+// NewFilter returns an empty builder
+func NewFilter() *FilterBuilder {
+	return &FilterBuilder{}
+}
+
+// Field adds a field condition, eg. Field("gameCD", 1) or Field("seriesCD", bson.D{{Key: "$in", Value: codes}})
+func (b *FilterBuilder) Field(key string, value interface{}) *FilterBuilder {
+	b.fields = append(b.fields, bson.E{Key: key, Value: value})
+	return b
+}
+
+// Condition adds a complete condition (eg. an $or-document) which is combined by $and
+func (b *FilterBuilder) Condition(condition bson.D) *FilterBuilder {
+	if len(condition) > 0 {
+		b.conditions = append(b.conditions, condition)
+	}
+	return b
+}
+
+// Visible restricts the result to the items the user is allowed to see
+// creatorKey is the path of the creator's ID within the documents (eg. "metaInfo.createdID")
+func (b *FilterBuilder) Visible(credentials *Credentials, creatorKey string) *FilterBuilder {
+	return b.Condition(VisibilityFilter(credentials, creatorKey))
+}
+
+// Build returns the filter document
+func (b *FilterBuilder) Build() bson.D {
+	filter := make(bson.D, len(b.fields), len(b.fields)+1)
+	copy(filter, b.fields)
+
+	if len(b.conditions) > 0 {
+		filter = append(filter, bson.E{Key: "$and", Value: b.conditions})
+	}
+
+	return filter
+}
+
+// VisibilityFilter is the query equivalent of GrantPermissions
+// - visitors see public items
+// - guests (unverified accounts) see public items and their own items
+// - members see public items, their own items and the items shared by their friends
+// - admins see everything (empty filter)
+// the items must use the field "visibilityCD"; the friendlist of the credentials must be loaded
+func VisibilityFilter(credentials *Credentials, creatorKey string) bson.D {
+
+	switch credentials.RoleCode {
+	case lookups.UserRoleAdmin:
+		// no visibility check needed for admins
+		return nil
+	case lookups.UserRoleGuest:
+		// anonymous visitors will only receive PUBLIC items
+		if credentials.UserID == primitive.NilObjectID {
+			return bson.D{{Key: "visibilityCD", Value: lookups.VisibilityAll}}
+		}
+		// registered guests also see their own items (like GrantPermissions)
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "visibilityCD", Value: lookups.VisibilityAll}},
+			bson.D{{Key: creatorKey, Value: credentials.UserID}},
+		}}}
+	default:
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "visibilityCD", Value: lookups.VisibilityAll}},
+			bson.D{{Key: creatorKey, Value: credentials.UserID}},
+			bson.D{
+				{Key: "visibilityCD", Value: lookups.VisibilityMembers},
+				{Key: creatorKey, Value: bson.D{{Key: "$in", Value: credentials.friendIDs()}}},
+			},
+		}}}
+	}
+}
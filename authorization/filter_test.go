@@ -0,0 +1,232 @@
+package authorization
+
+import (
+	"forza-garage/lookups"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testCreatorKey = "metaInfo.createdID"
+
+// test users
+var (
+	userAdmin  = primitive.NewObjectID()
+	userGuest  = primitive.NewObjectID()
+	userMember = primitive.NewObjectID()
+	userFriend = primitive.NewObjectID() // friend of userMember
+	userOther  = primitive.NewObjectID() // no relations
+)
+
+// an item in the list (visibility and creator)
+type testItem struct {
+	name       string
+	visibility int
+	creator    primitive.ObjectID
+}
+
+// one item per creator and visibility
+var testItems = func() []testItem {
+	creators := map[string]primitive.ObjectID{
+		"guest":  userGuest,
+		"member": userMember,
+		"friend": userFriend,
+		"other":  userOther,
+	}
+	visibilities := map[string]int{
+		"public":  lookups.VisibilityAll,
+		"members": lookups.VisibilityMembers,
+		"private": lookups.VisibilityNone,
+	}
+
+	var items []testItem
+	for c, creator := range creators {
+		for v, visibility := range visibilities {
+			items = append(items, testItem{name: c + "/" + v, visibility: visibility, creator: creator})
+		}
+	}
+	return items
+}()
+
+func (i testItem) document() bson.M {
+	return bson.M{
+		"visibilityCD": i.visibility,
+		testCreatorKey: i.creator,
+	}
+}
+
+func TestVisibilityFilter(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		credentials *Credentials
+		visible     []string
+	}{
+		{
+			name:        "visitor",
+			credentials: &Credentials{RoleCode: lookups.UserRoleGuest},
+			visible:     []string{"guest/public", "member/public", "friend/public", "other/public"},
+		},
+		{
+			name:        "guest",
+			credentials: &Credentials{UserID: userGuest, RoleCode: lookups.UserRoleGuest},
+			visible: []string{"guest/public", "member/public", "friend/public", "other/public",
+				"guest/members", "guest/private"},
+		},
+		{
+			name: "member",
+			credentials: &Credentials{UserID: userMember, RoleCode: lookups.UserRoleMember,
+				Friends: []UserRef{{UserID: userMember, ReferenceID: userFriend}}},
+			visible: []string{"guest/public", "member/public", "friend/public", "other/public",
+				"member/members", "member/private", "friend/members"},
+		},
+		{
+			name: "friend",
+			credentials: &Credentials{UserID: userFriend, RoleCode: lookups.UserRoleMember,
+				Friends: []UserRef{{UserID: userFriend, ReferenceID: userMember}}},
+			visible: []string{"guest/public", "member/public", "friend/public", "other/public",
+				"friend/members", "friend/private", "member/members"},
+		},
+		{
+			name:        "member without friends",
+			credentials: &Credentials{UserID: userOther, RoleCode: lookups.UserRoleMember},
+			visible: []string{"guest/public", "member/public", "friend/public", "other/public",
+				"other/members", "other/private"},
+		},
+		{
+			name:        "admin",
+			credentials: &Credentials{UserID: userAdmin, RoleCode: lookups.UserRoleAdmin},
+			visible: []string{"guest/public", "member/public", "friend/public", "other/public",
+				"guest/members", "member/members", "friend/members", "other/members",
+				"guest/private", "member/private", "friend/private", "other/private"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := VisibilityFilter(tt.credentials, testCreatorKey)
+
+			if tt.credentials.RoleCode == lookups.UserRoleAdmin && filter != nil {
+				t.Errorf("admin filter = %v, want nil", filter)
+			}
+
+			want := make(map[string]bool)
+			for _, name := range tt.visible {
+				want[name] = true
+			}
+
+			for _, item := range testItems {
+				// the filter is used by the builder (see Visible)
+				got := matches(t, item.document(), NewFilter().Condition(filter).Build())
+				if got != want[item.name] {
+					t.Errorf("%s: visible = %v, want %v", item.name, got, want[item.name])
+				}
+			}
+		})
+	}
+}
+
+func TestVisibilityFilterPublicOnlyForVisitors(t *testing.T) {
+	filter := VisibilityFilter(&Credentials{RoleCode: lookups.UserRoleGuest}, testCreatorKey)
+
+	want := bson.D{{Key: "visibilityCD", Value: lookups.VisibilityAll}}
+	if len(filter) != 1 || filter[0].Key != want[0].Key || filter[0].Value != want[0].Value {
+		t.Errorf("visitor filter = %v, want %v", filter, want)
+	}
+}
+
+func TestBuildNoRepeatedKeys(t *testing.T) {
+	member := &Credentials{UserID: userMember, RoleCode: lookups.UserRoleMember,
+		Friends: []UserRef{{UserID: userMember, ReferenceID: userFriend}}}
+
+	filter := NewFilter().
+		Field("gameCD", 1).
+		Field("metaInfo.deletedTS", bson.D{{Key: "$exists", Value: false}}).
+		Condition(bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "name", Value: "a"}},
+			bson.D{{Key: "name", Value: "b"}},
+		}}}).
+		Visible(member, testCreatorKey).
+		Condition(nil). // ignored
+		Build()
+
+	keys := make(map[string]int)
+	for _, e := range filter {
+		keys[e.Key]++
+	}
+	for key, n := range keys {
+		if n > 1 {
+			t.Errorf("key %q repeated %d times in %v", key, n, filter)
+		}
+	}
+
+	// both $or conditions are kept within $and
+	and, ok := filter.Map()["$and"].(bson.A)
+	if !ok || len(and) != 2 {
+		t.Errorf("$and = %v, want 2 conditions", filter.Map()["$and"])
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	filter := NewFilter().Visible(&Credentials{RoleCode: lookups.UserRoleAdmin}, testCreatorKey).Build()
+	if len(filter) != 0 {
+		t.Errorf("admin filter = %v, want empty", filter)
+	}
+}
+
+// matches evaluates the subset of the query language used by the filters
+// (equality, $in, $or, $and) against a document
+func matches(t *testing.T, doc bson.M, filter bson.D) bool {
+	for _, e := range filter {
+		switch e.Key {
+		case "$and":
+			for _, c := range e.Value.(bson.A) {
+				if !matches(t, doc, c.(bson.D)) {
+					return false
+				}
+			}
+		case "$or":
+			any := false
+			for _, c := range e.Value.(bson.A) {
+				if matches(t, doc, c.(bson.D)) {
+					any = true
+					break
+				}
+			}
+			if !any {
+				return false
+			}
+		default:
+			if !matchesValue(t, doc[e.Key], e.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchesValue(t *testing.T, value interface{}, condition interface{}) bool {
+	op, ok := condition.(bson.D)
+	if !ok {
+		return value == condition
+	}
+	for _, e := range op {
+		switch e.Key {
+		case "$in":
+			found := false
+			for _, id := range e.Value.([]primitive.ObjectID) {
+				if value == id {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		default:
+			t.Fatalf("operator %s not supported by the test", e.Key)
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package authorization
+
+import (
+	"context"
+	"forza-garage/apperror"
+	"forza-garage/lookups"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GrantPermissions enforces access rights to a single item
+// lists use the VisibilityFilter which applies the same rules within the query
+func GrantPermissions(itemVisibilityCode int32, itemCreatorID primitive.ObjectID, credentials *Credentials) error {
+
+	if credentials.RoleCode == lookups.UserRoleAdmin {
+		return nil
+	}
+
+	if itemCreatorID == credentials.UserID {
+		return nil
+	}
+
+	if itemVisibilityCode == lookups.VisibilityMembers && credentials.RoleCode == lookups.UserRoleGuest {
+		// get a log-in and make friends
+		return apperror.ErrGuest
+	}
+
+	if itemVisibilityCode == lookups.VisibilityMembers && !credentials.IsFriend(itemCreatorID) {
+		// make friends with them
+		return apperror.ErrNotFriend
+	}
+
+	if itemVisibilityCode == lookups.VisibilityNone {
+		// ask them to share
+		return apperror.ErrPrivate
+	}
+
+	// all checks passed
+	return nil
+}
+
+// IsFriend checks the (loaded) friendlist for a given user
+// if enabled by LookupFriendship, the friendship is looked up in the database instead
+func (c *Credentials) IsFriend(userOID primitive.ObjectID) bool {
+	if c.friendsRead || !c.lookupFriend {
+		for _, friend := range c.Friends {
+			if friend.ReferenceID == userOID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return findFriendship(c.socialCol, c.UserID, userOID)
+}
+
+// findFriendship looks up a friendship (errors mean "no friend"), replaced by tests
+var findFriendship = func(socialCol *mongo.Collection, userOID primitive.ObjectID, friendOID primitive.ObjectID) bool {
+
+	// friendships are stored once, in either direction
+	filter := bson.D{
+		{Key: "relType", Value: "friend"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "userID", Value: userOID}, {Key: "refID", Value: friendOID}},
+			bson.D{{Key: "userID", Value: friendOID}, {Key: "refID", Value: userOID}},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	count, err := socialCol.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false
+	}
+
+	return count > 0
+}
+
+// friendIDs returns the IDs of the (loaded) friendlist
+func (c *Credentials) friendIDs() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(c.Friends))
+	for i, friend := range c.Friends {
+		ids[i] = friend.ReferenceID
+	}
+	return ids
+}
@@ -0,0 +1,81 @@
+package authorization
+
+import (
+	"forza-garage/apperror"
+	"forza-garage/lookups"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestGrantPermissionsFriends(t *testing.T) {
+
+	// userFriend and userMember are friends (stored in the database)
+	queries := 0
+	defer func(f func(*mongo.Collection, primitive.ObjectID, primitive.ObjectID) bool) { findFriendship = f }(findFriendship)
+	findFriendship = func(socialCol *mongo.Collection, userOID primitive.ObjectID, friendOID primitive.ObjectID) bool {
+		queries++
+		return (userOID == userFriend && friendOID == userMember) || (userOID == userMember && friendOID == userFriend)
+	}
+
+	// as read by GetCredentials without the friendlist
+	credentials := func(userID primitive.ObjectID, roleCode int32) *Credentials {
+		return &Credentials{UserID: userID, RoleCode: roleCode, socialCol: &mongo.Collection{}}
+	}
+
+	tests := []struct {
+		name        string
+		credentials *Credentials
+		lookup      bool // a database lookup is expected
+		want        error
+	}{
+		{
+			name:        "friend reads (GetCourse)",
+			credentials: credentials(userFriend, lookups.UserRoleMember).LookupFriendship(),
+			lookup:      true,
+			want:        nil,
+		},
+		{
+			name:        "friend updates (UpdateCourse)",
+			credentials: credentials(userFriend, lookups.UserRoleMember),
+			want:        apperror.ErrNotFriend,
+		},
+		{
+			name:        "other member reads",
+			credentials: credentials(userOther, lookups.UserRoleMember).LookupFriendship(),
+			lookup:      true,
+			want:        apperror.ErrNotFriend,
+		},
+		{
+			name:        "creator updates",
+			credentials: credentials(userMember, lookups.UserRoleMember),
+			want:        nil,
+		},
+		{
+			name:        "admin updates",
+			credentials: credentials(userAdmin, lookups.UserRoleAdmin),
+			want:        nil,
+		},
+		{
+			name:        "guest reads",
+			credentials: credentials(userGuest, lookups.UserRoleGuest).LookupFriendship(),
+			want:        apperror.ErrGuest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries = 0
+
+			// a members-only course of userMember
+			err := GrantPermissions(lookups.VisibilityMembers, userMember, tt.credentials)
+			if err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if (queries > 0) != tt.lookup {
+				t.Errorf("database queries = %d, want lookup %v", queries, tt.lookup)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"forza-garage/environment"
+	"forza-garage/models"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runCommand executes a maintenance task instead of starting the server
+func runCommand(args []string) error {
+
+	switch args[0] {
+	case "import":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: forza-garage import <file.json|file.csv> <admin user ID>")
+		}
+		return importCourses(args[1], args[2])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// importCourses reads courses from a file, the format is given by its extension
+func importCourses(fileName string, userID string) error {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format := models.TransferFormatJSON
+	if strings.EqualFold(filepath.Ext(fileName), ".csv") {
+		format = models.TransferFormatCSV
+	}
+
+	rows, err := models.ReadCourseTransfers(file, format)
+	if err != nil {
+		return err
+	}
+
+	result, err := environment.Env.CourseModel.ImportCourses(rows, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range result.Errors {
+		fmt.Printf("row %v (%v): %v\n", e.Row, e.Name, e.Message)
+	}
+	fmt.Printf("%v of %v courses imported\n", result.Imported, len(rows))
+
+	return nil
+}
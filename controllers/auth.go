@@ -113,9 +113,9 @@ func Register(c *gin.Context) {
 	data.Password = strings.TrimSpace(data.Password)
 	data.EMailAddress = strings.TrimSpace(data.EMailAddress) // ToDo: perhaps check for valid form
 
-	// basically look for missing fields
-	// len(data.LoginName) < 3|len(data.Password < 8|len(data.EMailAddress == 0)
-	if len(data.LoginName) < 3 || len(data.Password) < 8 || len(data.EMailAddress) == 0 {
+	// basically look for missing fields - the password rules are checked by the model (PasswordPolicy)
+	// len(data.LoginName) < 3|len(data.Password == 0)|len(data.EMailAddress == 0)
+	if len(data.LoginName) < 3 || len(data.Password) == 0 || len(data.EMailAddress) == 0 {
 		apiError.Code = InvalidRequest
 		apiError.Message = apiError.String(apiError.Code)
 		c.JSON(http.StatusUnprocessableEntity, apiError)
@@ -132,6 +132,13 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// the account stays guest until the eMail-Address is verified
+	// a lost mail can be sent again (ResendVerification), hence the registration succeeds anyway
+	err = sendVerificationMail(ID, data.LoginName, data.EMailAddress)
+	if err != nil {
+		fmt.Println(err) // ToDO: log
+	}
+
 	c.JSON(http.StatusOK, Created{ID})
 }
 
@@ -190,8 +197,40 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// create, register & save pair of AT/RT
-	err = authentication.CreateTokens(c, dbUser.ID.Hex())
+	// second step: the code of the authenticator app is sent to /login/2fa along with the challenge
+	// no tokens (cookie) are issued before
+	if dbUser.TwoFactorEnabled {
+		// no new challenges (guesses) after too many wrong codes
+		err = authentication.CheckTwoFactorFailures(dbUser.ID.Hex())
+		if err != nil {
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+			return
+		}
+
+		challenge, err := authentication.CreateOneTimeToken(authentication.PurposeLoginChallenge, dbUser.ID.Hex(), authentication.LoginChallengeTTL)
+		if err != nil {
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+			return
+		}
+
+		res := struct {
+			TwoFactorRequired bool   `json:"twoFactorRequired"`
+			Challenge         string `json:"challenge"`
+		}{true, challenge}
+
+		c.JSON(http.StatusAccepted, res)
+		return
+	}
+
+	startSession(c, dbUser)
+}
+
+// new session (device), sends the pair of AT/RT and the user's account
+func startSession(c *gin.Context, dbUser *models.User) {
+
+	err := authentication.CreateSession(c, dbUser.ID.Hex(), getIP(c.Request))
 	if err != nil {
 		status, apiError := HandleError(err)
 		c.JSON(status, apiError)
@@ -216,20 +255,20 @@ func Logout(c *gin.Context) {
 	// Damit im Client der CurrentUser (LocalStorage) und das Cookie gelöscht
 	// werden können, soll das API keinen Fehler liefern
 
-	au, _ := authentication.ExtractTokenMetadata(authentication.AT, c.Request)
-
-	// nur at löschen, rt bleibt bestehen
-	// in case of error the token might be expired
-	_, _ = authentication.DeleteAuth(au.TokenUUID)
-
-	// "Hard log-out" => AT, RT & Cookie löschen => auf allen Geräten ausloggen
-	// auch für Testzwecke nützlich
-
-	au, _ = authentication.ExtractTokenMetadata(authentication.RT, c.Request)
-
-	// rt löschen
+	// the session is revoked with both of its tokens (AT & RT)
 	// in case of error the token might be expired
-	_, _ = authentication.DeleteAuth(au.TokenUUID)
+	au, err := authentication.ExtractTokenMetadata(authentication.AT, c.Request)
+	if err == nil {
+		if au.SessionID != "" {
+			_ = authentication.RevokeSession(au.UserID, au.SessionID)
+		} else {
+			// tokens issued before sessions were introduced
+			_, _ = authentication.DeleteAuth(au.TokenUUID)
+			if rt, err := authentication.ExtractTokenMetadata(authentication.RT, c.Request); err == nil {
+				_, _ = authentication.DeleteAuth(rt.TokenUUID)
+			}
+		}
+	}
 
 	// Cookie löschen
 	_ = helpers.DelCookie(c, os.Getenv("JWTCK_NAME"))
@@ -258,10 +297,11 @@ func Refresh(c *gin.Context) {
 	}
 
 	// userID für die Ausstellung eines neues Token Pair
-	userID, err := authentication.FetchAuth(au)
+	// a rotated (re-used) token revokes its session
+	userID, err := authentication.CheckRefreshToken(au)
 	if err != nil {
-		status, apiError := HandleError(err)
-		c.JSON(status, apiError)
+		_, apiError = HandleError(err)
+		c.JSON(http.StatusUnauthorized, apiError)
 		return
 	}
 
@@ -280,19 +320,9 @@ func Refresh(c *gin.Context) {
 		return
 	}
 
-	// falls zu viele RTs (Clients) für den User in Umlauf sind, alle löschen, sonst nur das aktuelle
-	// die ATs werden stehen gelassen; diese Clients können also noch damit arbeiten
-	// ein neuer Refresh wird dann aber nicht mehr gehen
-	deleted, err := authentication.DeleteAuths(authentication.RT, userID, au.TokenUUID)
-	if err != nil || deleted == 0 { //if anything goes wrong
-		apiError.Code = InvalidRequest
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-
-	// create, register & save pair of AT/RT
-	err = authentication.CreateTokens(c, userID)
+	// the session keeps its ID, the previous tokens are replaced
+	// revoked or expired sessions can't be refreshed, the client needs to log-in again
+	err = authentication.RefreshSession(c, au, getIP(c.Request))
 	if err != nil {
 		_, apiError = HandleError(err)
 		c.JSON(http.StatusUnauthorized, apiError)
@@ -408,8 +438,8 @@ func ChangePassword(c *gin.Context) {
 	data.CurrentPWD = strings.TrimSpace(data.CurrentPWD)
 	data.NewPassword = strings.TrimSpace(data.NewPassword)
 
-	// look for empty fields (Gin does not trim)
-	if len(data.LoginName) == 0 || len(data.CurrentPWD) == 0 || len(data.NewPassword) < 8 {
+	// look for empty fields (Gin does not trim) - the new password is validated by SetPassword
+	if len(data.LoginName) == 0 || len(data.CurrentPWD) == 0 || len(data.NewPassword) == 0 {
 		apiError.Code = InvalidRequest
 		apiError.Message = apiError.String(apiError.Code)
 		c.JSON(http.StatusUnauthorized, apiError)
@@ -450,7 +480,7 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// ToDo: Validate new PWD (or include that in SetPWD)
+	// policy and recent passwords are checked by the model
 	err = environment.Env.UserModel.SetPassword(dbUser.ID, data.NewPassword)
 	if err != nil {
 		status, apiError := HandleError(err)
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"errors"
+	"forza-garage/apperror"
+	"forza-garage/authentication"
+	"forza-garage/environment"
+	"forza-garage/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddChampionship creates a new championship
+func AddChampionship(c *gin.Context) {
+
+	var (
+		err      error
+		data     models.Championship
+		apiError ErrorResponse
+	)
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// use "shouldBind" not all fields are required in this context
+	if err = c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	// validate request
+	championship, err := environment.Env.ChampionshipModel.Validate(data)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	id, err := environment.Env.ChampionshipModel.CreateChampionship(championship, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, Created{id})
+}
+
+// ListChampionshipsPublic returns a list of championships
+// format => http://localhost:3000/championships/public?game=0&series=0&series=2&search=test
+func ListChampionshipsPublic(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	// no user available/needed for the public service
+	userID := ""
+
+	search, err := bindChampionshipSearch(c)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	championships, err := environment.Env.ChampionshipModel.SearchChampionships(search, userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, championships)
+}
+
+// ListChampionshipsMember returns a list of championships for logged-in users
+// format => http://localhost:3000/championships/member?game=0&series=0&series=2&search=test
+func ListChampionshipsMember(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	search, err := bindChampionshipSearch(c)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	championships, err := environment.Env.ChampionshipModel.SearchChampionships(search, userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, championships)
+}
+
+// GetChampionshipPublic returns the specified championship
+func GetChampionshipPublic(c *gin.Context) {
+
+	// no user available/required for the public service
+	userID := ""
+
+	var id = c.Param("id")
+
+	data, err := environment.Env.ChampionshipModel.GetChampionship(id, userID)
+	if err != nil {
+		switch err {
+		// record not found is not an error to the client here
+		case apperror.ErrNoData:
+			c.Status(http.StatusNoContent)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+
+	// log this request, if it was a new one
+	if environment.Env.Requests.Continue(getIP(c.Request), id) {
+		environment.Env.Tracker.SaveVisitor("championship", id, userID)
+	}
+}
+
+// GetChampionshipMember returns the specified championship for logged-in users
+func GetChampionshipMember(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var id = c.Param("id")
+
+	data, err := environment.Env.ChampionshipModel.GetChampionship(id, userID)
+	if err != nil {
+		switch err {
+		// record not found is not an error to the client here
+		case apperror.ErrNoData:
+			c.Status(http.StatusNoContent)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+
+	// log this request, if it was a new one
+	if environment.Env.Requests.Continue(getIP(c.Request), id) {
+		environment.Env.Tracker.SaveVisitor("championship", id, userID)
+	}
+}
+
+// UpdateChampionship modifies "core" fields and the line-up
+func UpdateChampionship(c *gin.Context) {
+
+	var (
+		err      error
+		data     models.Championship
+		apiError ErrorResponse
+	)
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// the ID is taken from the body (as for courses)
+	if err = c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	championship, err := environment.Env.ChampionshipModel.Validate(data)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	err = environment.Env.ChampionshipModel.UpdateChampionship(championship, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// reads the query parameters shared by the public and member listings
+func bindChampionshipSearch(c *gin.Context) (*models.ChampionshipSearchParams, error) {
+
+	search := new(models.ChampionshipSearchParams)
+
+	i, err := strconv.Atoi(c.Query("game"))
+	if err != nil {
+		return nil, err
+	}
+	search.GameCode = int32(i)
+
+	// variable wiederholt sich einfach im url
+	for _, str := range c.QueryArray("series") {
+		i, err = strconv.Atoi(str)
+		// ignore invalid codes
+		if err == nil {
+			search.SeriesCodes = append(search.SeriesCodes, int32(i))
+		}
+	}
+	if search.SeriesCodes == nil {
+		return nil, errors.New("series missing")
+	}
+
+	search.SearchTerm = c.Query("search")
+
+	return search, nil
+}
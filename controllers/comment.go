@@ -103,3 +103,54 @@ func ListCommentsMember(c *gin.Context) {
 
 	c.JSON(http.StatusOK, comments)
 }
+
+// DeleteComment moves a comment or reply to the trash bin
+// (generic handler for all profile types)
+func DeleteComment(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	err = environment.Env.CommentModel.DeleteComment(c.Param("id"), userID)
+	if err != nil {
+		switch err {
+		// already gone (or not the user's comment) is not an error to the client here
+		case apperror.ErrNoData:
+			c.Status(http.StatusNoContent)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreComment takes a comment or reply out of the trash bin
+func RestoreComment(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	err = environment.Env.CommentModel.RestoreComment(c.Param("id"), userID)
+	if err != nil {
+		switch err {
+		// nothing to restore (expired or not the user's comment)
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,14 +1,19 @@
 package controllers
 
 import (
+	"errors"
 	"forza-garage/apperror"
 	"forza-garage/authentication"
 	"forza-garage/environment"
+	"forza-garage/helpers"
 	"forza-garage/models"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // AddCourse creates a new route
@@ -54,7 +59,9 @@ func AddCourse(c *gin.Context) {
 }
 
 // ListCoursesPublic returns a list of racing tracks
-// format => http://localhost:3000/courses/public?searchMode=2&game=0&series=0&series=2&search=test
+// format => http://localhost:3000/courses/public?searchMode=2&game=0&series=0&series=2&search=test&cursor=...&limit=20
+// optional filters => &class=1&class=2&style=0&tag=dirt&tag=night&creator=5feb25fa266749192452cc08
+// &createdFrom=2021-01-01&createdTo=2021-01-31&modifiedFrom=...&modifiedTo=...&sort=top|newest|visits
 func ListCoursesPublic(c *gin.Context) {
 
 	var apiError ErrorResponse
@@ -64,50 +71,13 @@ func ListCoursesPublic(c *gin.Context) {
 	// the model will assign the default profile/role to it, without the need of a DB access
 	userID := ""
 
-	//var search *models.CourseSearchParams
-	search := new(models.CourseSearchParams)
-
-	i, err := strconv.Atoi(c.Query("searchMode"))
+	search, err := bindCourseSearch(c)
 	if err != nil {
 		apiError.Code = InvalidJSON
 		apiError.Message = apiError.String(apiError.Code)
 		c.JSON(http.StatusUnprocessableEntity, apiError)
 		return
 	}
-	search.SearchMode = i
-
-	i, err = strconv.Atoi(c.Query("game"))
-	if err != nil {
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-	search.GameCode = int32(i)
-
-	// variable wiederholt sich einfach im url
-	series := c.QueryArray("series")
-	if series == nil {
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-	for _, str := range series {
-		i, err = strconv.Atoi(str)
-		// ignore invalid codes
-		if err == nil {
-			search.SeriesCodes = append(search.SeriesCodes, int32(i))
-		}
-	}
-	if search.SeriesCodes == nil {
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-
-	search.SearchTerm = c.Query("search")
 
 	// ToDo: Lang
 	// use language submitted by client for anonymous users (rather than the one stored in database)
@@ -118,10 +88,6 @@ func ListCoursesPublic(c *gin.Context) {
 		}
 	*/
 
-	// nötig?
-	// searchTerm = strings.TrimSpace(data.SearchTerm)
-	// fmt.Println(data.SearchTerm)
-
 	courses, err := environment.Env.CourseModel.SearchCourses(search, userID)
 	if err != nil {
 		// nothing found (not an error to the client)
@@ -142,73 +108,29 @@ func ListCoursesPublic(c *gin.Context) {
 	c.JSON(http.StatusOK, courses)
 
 	// log the request
-	environment.Env.Tracker.SaveSearchCourse(search, courses)
+	environment.Env.Tracker.SaveSearchCourse(search, courses.Courses)
 }
 
 // ListCoursesMember returns a list of racing tracks for logged-in users
-// format => http://localhost:3000/courses/member?searchMode=2&game=0&series=0&series=2&search=test
+// format => http://localhost:3000/courses/member?searchMode=2&game=0&series=0&series=2&search=test&cursor=...&limit=20
+// (same optional filters as the public list)
 func ListCoursesMember(c *gin.Context) {
 
 	var apiError ErrorResponse
 
-	/*apiError.Code = InvalidJSON
-	apiError.Message = apiError.String(apiError.Code)
-	c.JSON(http.StatusUnprocessableEntity, apiError)
-	return
-
-	/*c.Status(http.StatusInternalServerError)
-	return*/
-
 	userID, err := authentication.Authenticate(c.Request)
 	if err != nil {
 		c.Status(http.StatusUnauthorized)
 		return
 	}
 
-	//var search *models.CourseSearchParams
-	search := new(models.CourseSearchParams)
-
-	i, err := strconv.Atoi(c.Query("searchMode"))
+	search, err := bindCourseSearch(c)
 	if err != nil {
 		apiError.Code = InvalidJSON
 		apiError.Message = apiError.String(apiError.Code)
 		c.JSON(http.StatusUnprocessableEntity, apiError)
 		return
 	}
-	search.SearchMode = i
-
-	i, err = strconv.Atoi(c.Query("game"))
-	if err != nil {
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-	search.GameCode = int32(i)
-
-	// variable wiederholt sich einfach im url
-	series := c.QueryArray("series")
-	if series == nil {
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-	for _, str := range series {
-		i, err = strconv.Atoi(str)
-		// ignore invalid codes
-		if err == nil {
-			search.SeriesCodes = append(search.SeriesCodes, int32(i))
-		}
-	}
-	if search.SeriesCodes == nil {
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return
-	}
-
-	search.SearchTerm = c.Query("search")
 
 	// ToDo: Language
 	// use language submitted by client for anonymous users (rather than the one stored in database)
@@ -219,10 +141,6 @@ func ListCoursesMember(c *gin.Context) {
 		}
 	*/
 
-	// nötig?
-	// searchTerm = strings.TrimSpace(data.SearchTerm)
-	// fmt.Println(data.SearchTerm)
-
 	courses, err := environment.Env.CourseModel.SearchCourses(search, userID)
 	if err != nil {
 		// nothing found (not an error to the client)
@@ -239,7 +157,7 @@ func ListCoursesMember(c *gin.Context) {
 	c.JSON(http.StatusOK, courses)
 
 	// log the request
-	environment.Env.Tracker.SaveSearchCourse(search, courses)
+	environment.Env.Tracker.SaveSearchCourse(search, courses.Courses)
 }
 
 // GetCoursePublic returns the specified track
@@ -409,9 +327,297 @@ func UpdateCourse(c *gin.Context) {
 	c.Status(http.StatusNoContent) // evtl. auch 205
 }
 
+// DeleteCourse moves a course to the trash bin
+// the record version is passed as a query parameter (DELETE has no body)
+// the route is /courses/member/:id instead of /courses/:id - the router doesn't accept a wildcard (:id)
+// next to the static "member" segment, which DELETE /courses/member/:id/uploads/:fid already uses
+// format => http://localhost:3000/courses/member/5feb25fa266749192452cc08?recVer=3
+func DeleteCourse(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	recVer, err := strconv.ParseInt(c.Query("recVer"), 10, 64)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = environment.Env.CourseModel.DeleteCourse(c.Param("id"), recVer, userID)
+	if err != nil {
+		switch err {
+		// already gone is not an error to the client here
+		case apperror.ErrNoData:
+			c.Status(http.StatusNoContent)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreCourse takes a course out of the trash bin
+func RestoreCourse(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	err = environment.Env.CourseModel.RestoreCourse(c.Param("id"), userID)
+	if err != nil {
+		switch err {
+		// nothing to restore (expired or not the user's course)
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeletedCourses returns the courses in the trash bin of the current user
+func ListDeletedCourses(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	courses, err := environment.Env.CourseModel.ListDeletedCourses(userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, courses)
+}
+
+// ForkCourse creates a custom course derived from an existing one
+func ForkCourse(c *gin.Context) {
+
+	var (
+		err      error
+		data     models.CourseFork
+		apiError ErrorResponse
+	)
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	if err = c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	id, err := environment.Env.CourseModel.ForkCourse(c.Param("id"), &data, userID)
+	if err != nil {
+		switch err {
+		// original not found
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, Created{id})
+}
+
+// ListForksPublic returns the courses derived from a given course
+func ListForksPublic(c *gin.Context) {
+
+	// no user available/required for the public service
+	userID := ""
+
+	courses, err := environment.Env.CourseModel.ListForks(c.Param("id"), userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, courses)
+}
+
+// ListForksMember returns the courses derived from a given course for logged-in users
+func ListForksMember(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	courses, err := environment.Env.CourseModel.ListForks(c.Param("id"), userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, courses)
+}
+
+// ListRevisions returns the version history of a course
+func ListRevisions(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	revisions, err := environment.Env.CourseModel.ListRevisions(c.Param("id"), userID)
+	if err != nil {
+		switch err {
+		// record not found is not an error to the client here
+		case apperror.ErrNoData:
+			c.Status(http.StatusNoContent)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// DiffRevisions lists the fields which differ between two versions of a course
+// format => http://localhost:3000/courses/member/5feb25fa266749192452cc08/revisions/diff?from=2&to=5
+func DiffRevisions(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	from, err := strconv.ParseInt(c.Query("from"), 10, 64)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	to, err := strconv.ParseInt(c.Query("to"), 10, 64)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	diffs, err := environment.Env.CourseModel.DiffRevisions(c.Param("id"), from, to, userID)
+	if err != nil {
+		switch err {
+		// course or version not found
+		case apperror.ErrNoData:
+			c.Status(http.StatusNoContent)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, diffs)
+}
+
+// RollbackCourse publishes an earlier version of a course again
+// the current record version is passed for optimistic locking (as for deletes)
+// format => http://localhost:3000/courses/5feb25fa266749192452cc08/rollback?to=2&recVer=5
+func RollbackCourse(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	to, err := strconv.ParseInt(c.Query("to"), 10, 64)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	recVer, err := strconv.ParseInt(c.Query("recVer"), 10, 64)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = environment.Env.CourseModel.RollbackCourse(c.Param("id"), to, recVer, userID)
+	if err != nil {
+		switch err {
+		// course or version not found
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // Additional & Helper Services
 
-// ExistsForzaShare checks if a given Forza Sharing Code is already in use
+// ExistsForzaShare checks if a given Forza Sharing Code is already in use in a game
 // (used for typing-checks in clients)
 func ExistsForzaShare(c *gin.Context) {
 
@@ -428,6 +634,7 @@ func ExistsForzaShare(c *gin.Context) {
 
 	// anonymous struct used to receive input (POST BODY)
 	data := struct {
+		GameCode     int32 `json:"GameCode"` // not "required", FH4 is 0
 		ForzaSharing int32 `json:"ForzaSharing" binding:"required"`
 	}{}
 
@@ -439,7 +646,7 @@ func ExistsForzaShare(c *gin.Context) {
 		return
 	}
 
-	exists, err := environment.Env.CourseModel.ForzaSharingExists(data.ForzaSharing)
+	exists, err := environment.Env.CourseModel.ForzaSharingExists(data.GameCode, data.ForzaSharing)
 	if err != nil {
 		status, apiError := HandleError(err)
 		c.JSON(status, apiError)
@@ -453,3 +660,159 @@ func ExistsForzaShare(c *gin.Context) {
 
 	c.JSON(http.StatusOK, res)
 }
+
+// GetCourseByShareCode returns the course of a share code copied from the game
+// format => http://localhost:3000/courses/by-share-code/123456789?game=1
+// works for guests and logged-in users, the visibility depends on the (optional) token
+func GetCourseByShareCode(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	// guests have no token, the model assigns the default profile to them
+	userID, _ := authentication.Authenticate(c.Request)
+
+	// share codes are entered as shown in the game, eg. "123 456 789"
+	code, err := strconv.Atoi(strings.ReplaceAll(c.Param("code"), " ", ""))
+	if err != nil {
+		apiError.Code = ForzaShareInvalid
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	game, err := strconv.Atoi(c.DefaultQuery("game", "0"))
+	if err != nil {
+		apiError.Code = InvalidRequest
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = models.ValidateForzaSharing(int32(game), int32(code))
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	data, err := environment.Env.CourseModel.GetCourseByShareCode(int32(game), int32(code), userID)
+	if err != nil {
+		switch err {
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+
+	// log this request, if it was a new one
+	id := data.ID.Hex()
+	if environment.Env.Requests.Continue(getIP(c.Request), id) {
+		environment.Env.Tracker.SaveVisitor("course", id, userID)
+	}
+}
+
+// reads the query parameters shared by the public and member listings
+func bindCourseSearch(c *gin.Context) (*models.CourseSearchParams, error) {
+
+	search := new(models.CourseSearchParams)
+
+	i, err := strconv.Atoi(c.Query("searchMode"))
+	if err != nil {
+		return nil, err
+	}
+	search.SearchMode = i
+
+	i, err = strconv.Atoi(c.Query("game"))
+	if err != nil {
+		return nil, err
+	}
+	search.GameCode = int32(i)
+
+	// variable wiederholt sich einfach im url
+	search.SeriesCodes = queryCodes(c, "series")
+	if search.SeriesCodes == nil {
+		return nil, errors.New("series missing")
+	}
+
+	search.SearchTerm = c.Query("search")
+
+	// optional filters
+	search.CarClasses = queryCodes(c, "class")
+	search.StyleCodes = queryCodes(c, "style")
+	search.Tags = c.QueryArray("tag")
+
+	if c.Query("creator") != "" {
+		search.CreatorID = helpers.ObjectID(c.Query("creator"))
+		if search.CreatorID == primitive.NilObjectID {
+			return nil, errors.New("invalid creator")
+		}
+	}
+
+	// date ranges (the end date is included)
+	if search.CreatedFrom, err = queryDate(c, "createdFrom", false); err != nil {
+		return nil, err
+	}
+	if search.CreatedTo, err = queryDate(c, "createdTo", true); err != nil {
+		return nil, err
+	}
+	if search.ModifiedFrom, err = queryDate(c, "modifiedFrom", false); err != nil {
+		return nil, err
+	}
+	if search.ModifiedTo, err = queryDate(c, "modifiedTo", true); err != nil {
+		return nil, err
+	}
+
+	search.SortOrder = c.Query("sort")
+
+	// paging
+	search.Cursor = c.Query("cursor")
+	if c.Query("limit") != "" {
+		i, err = strconv.Atoi(c.Query("limit"))
+		if err != nil {
+			return nil, err
+		}
+		search.Limit = int64(i)
+	}
+
+	return search, nil
+}
+
+// queryCodes reads a repeated numeric parameter (invalid codes are ignored)
+func queryCodes(c *gin.Context, key string) []int32 {
+
+	var codes []int32
+
+	for _, str := range c.QueryArray(key) {
+		i, err := strconv.Atoi(str)
+		if err == nil {
+			codes = append(codes, int32(i))
+		}
+	}
+
+	return codes
+}
+
+// queryDate reads an optional date parameter (YYYY-MM-DD)
+// end dates are moved to the following day, so they can be used as exclusive bounds
+func queryDate(c *gin.Context, key string, end bool) (*time.Time, error) {
+
+	if c.Query(key) == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse("2006-01-02", c.Query(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return &t, nil
+}
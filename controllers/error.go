@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"forza-garage/apperror"
+	"forza-garage/authentication"
 	"forza-garage/models"
 	"net/http"
 )
@@ -78,15 +79,68 @@ func HandleError(err error) (httpStatus int, apiError ErrorResponse) {
 		apiError.Code = InvalidPassword
 		apiError.Message = apiError.String(apiError.Code)
 		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrPasswordReused:
+		apiError.Code = PasswordReused
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrUserBlocked:
+		apiError.Code = UserBlocked
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrTwoFactorEnabled:
+		apiError.Code = TwoFactorEnabled
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrTwoFactorNotEnabled:
+		apiError.Code = TwoFactorNotEnabled
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrTwoFactorCodeInvalid:
+		apiError.Code = TwoFactorCodeInvalid
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case authentication.ErrTooManyFailures:
+		apiError.Code = TooManyFailures
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusTooManyRequests
+	case authentication.ErrInvalidToken:
+		apiError.Code = InvalidToken
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
 	// course
 	case models.ErrCourseNameMissing:
 		apiError.Code = CourseNameMissing
 		apiError.Message = apiError.String(apiError.Code)
 		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrForzaSharingCodeMissing:
+		apiError.Code = ForzaShareMissing
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
 	case models.ErrForzaSharingCodeTaken:
 		apiError.Code = ForzaShareTaken
 		apiError.Message = apiError.String(apiError.Code)
 		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrForzaSharingCodeInvalid:
+		apiError.Code = ForzaShareInvalid
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrInvalidCursor:
+		apiError.Code = InvalidRequest
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	// championship
+	case models.ErrChampionshipNameMissing:
+		apiError.Code = ChampionshipNameMissing
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrChampionshipRacesMissing:
+		apiError.Code = ChampionshipRacesMissing
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
+	case models.ErrChampionshipRaceInvalid:
+		apiError.Code = ChampionshipRaceInvalid
+		apiError.Message = apiError.String(apiError.Code)
+		httpStatus = http.StatusUnprocessableEntity
 	default:
 		apiError.Code = SystemError
 		apiError.Message = apiError.String(apiError.Code)
@@ -117,6 +171,21 @@ const (
 	// course
 	CourseNameMissing
 	ForzaShareTaken
+	// championship
+	ChampionshipNameMissing
+	ChampionshipRacesMissing
+	ChampionshipRaceInvalid
+	// course (added later, appended to keep the codes above)
+	ForzaShareMissing
+	ForzaShareInvalid
+	// user (added later)
+	UserBlocked
+	InvalidToken
+	PasswordReused
+	TwoFactorEnabled
+	TwoFactorNotEnabled
+	TwoFactorCodeInvalid
+	TooManyFailures
 	SystemError = 99999
 )
 
@@ -144,6 +213,8 @@ func (er ErrorResponse) String(code int32) string {
 	case PermissionPrivate:
 		msg = "item is private"
 	// user
+	case InvalidPassword:
+		msg = "password does not meet the requirements"
 	case InvalidFriend:
 		msg = "could not add or remove friend"
 	// course
@@ -151,6 +222,31 @@ func (er ErrorResponse) String(code int32) string {
 		msg = "course name is required"
 	case ForzaShareTaken:
 		msg = "Duplicate Forza Share Code"
+	// championship
+	case ChampionshipNameMissing:
+		msg = "championship name is required"
+	case ChampionshipRacesMissing:
+		msg = "at least one race is required"
+	case ChampionshipRaceInvalid:
+		msg = "race not available"
+	case ForzaShareMissing:
+		msg = "Forza Share Code is required"
+	case ForzaShareInvalid:
+		msg = "Invalid Forza Share Code"
+	case UserBlocked:
+		msg = "user is blocked"
+	case InvalidToken:
+		msg = "link is invalid or has expired"
+	case PasswordReused:
+		msg = "password was used recently"
+	case TwoFactorEnabled:
+		msg = "two-factor authentication is already enabled"
+	case TwoFactorNotEnabled:
+		msg = "two-factor authentication is not enabled"
+	case TwoFactorCodeInvalid:
+		msg = "invalid code"
+	case TooManyFailures:
+		msg = "too many wrong codes, try again later"
 	case SystemError:
 		msg = "Server Problem"
 	}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"forza-garage/apperror"
+	"forza-garage/authentication"
+	"forza-garage/environment"
+	"forza-garage/events"
+	"forza-garage/helpers"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// profiles a client may subscribe to with one stream
+const maxEventProfiles = 20
+
+// StreamEvents pushes live updates to the logged-in user (server-sent events)
+// the user's notifications are always sent, vote counts for the given profiles (eg. the course being viewed)
+// profiles the user isn't allowed to see are rejected
+// format => http://localhost:3000/events?profile=5feb25fa266749192452cc08&profile=...
+func StreamEvents(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	profiles := c.QueryArray("profile")
+	if len(profiles) > maxEventProfiles {
+		apiError.Code = InvalidRequest
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	topics := []string{events.UserTopic(userID)}
+	for _, p := range profiles {
+		if helpers.ObjectID(p) == primitive.NilObjectID {
+			apiError.Code = InvalidRequest
+			apiError.Message = apiError.String(apiError.Code)
+			c.JSON(http.StatusUnprocessableEntity, apiError)
+			return
+		}
+		// vote counts of private items (or items shared with friends) are only sent to users who can see them
+		err = environment.Env.CourseModel.CheckProfileAccess(p, userID)
+		if err != nil {
+			switch err {
+			case apperror.ErrNoData:
+				c.Status(http.StatusNotFound)
+			default:
+				status, apiError := HandleError(err)
+				c.JSON(status, apiError)
+			}
+			return
+		}
+		topics = append(topics, events.ProfileTopic(p))
+	}
+
+	ch, unsubscribe := environment.Env.Events.Subscribe(topics)
+	defer unsubscribe()
+
+	// keeps proxies from closing an idle connection and ends the stream with the session
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx: don't buffer the stream
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Name, event.Data)
+			return true
+		case <-heartbeat.C:
+			// logged out or token expired, the client reconnects after refreshing it
+			_, err := authentication.Authenticate(c.Request)
+			if err != nil {
+				return false
+			}
+			// comment line, ignored by the clients
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"forza-garage/apperror"
+	"forza-garage/authentication"
+	"forza-garage/environment"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotifications returns a page of the user's notifications (newest first)
+// format => http://localhost:3000/user/notifications?cursor=...&limit=20&unread=true
+func ListNotifications(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if c.Query("limit") != "" {
+		limit, err = strconv.Atoi(c.Query("limit"))
+		if err != nil {
+			apiError.Code = InvalidJSON
+			apiError.Message = apiError.String(apiError.Code)
+			c.JSON(http.StatusUnprocessableEntity, apiError)
+			return
+		}
+	}
+
+	notifications, err := environment.Env.NotificationModel.ListNotifications(userID, c.Query("cursor"), limit, c.Query("unread") == "true")
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// CountUnreadNotifications returns the number of unread notifications (eg. for a badge)
+func CountUnreadNotifications(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	count, err := environment.Env.NotificationModel.CountUnread(userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	// wrap response into an object
+	res := struct {
+		Unread int64 `json:"unread"`
+	}{count}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// MarkNotificationRead flags a notification as read
+func MarkNotificationRead(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// anonymous struct used to receive input (POST BODY)
+	data := struct {
+		NotificationID string `json:"notificationID" binding:"required"`
+	}{}
+
+	// use 'shouldBind' so we can send customized messages
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = environment.Env.NotificationModel.MarkRead(data.NotificationID, userID)
+	if err != nil {
+		switch err {
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead flags all notifications of the user as read
+func MarkAllNotificationsRead(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	err = environment.Env.NotificationModel.MarkAllRead(userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
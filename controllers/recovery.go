@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"fmt"
+	"forza-garage/authentication"
+	"forza-garage/environment"
+	"forza-garage/mail"
+	"forza-garage/models"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ForgotPassword sends a link to set a new password to the eMail-Address of an account
+// the response is always the same, so it can't be used to find out who's registered
+func ForgotPassword(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	data := struct {
+		EMailAddress string `json:"eMailAddress" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	// the mail is sent in the background (same response time for unknown addresses)
+	go sendPasswordResetMail(strings.TrimSpace(data.EMailAddress))
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResetPassword sets a new password using the token of a reset mail
+// the user is logged out of all devices
+func ResetPassword(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	data := struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"newPWD" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	data.Token = strings.TrimSpace(data.Token)
+	data.NewPassword = strings.TrimSpace(data.NewPassword)
+	if len(data.NewPassword) == 0 {
+		apiError.Code = InvalidRequest
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	userID, err := authentication.PeekOneTimeToken(authentication.PurposePasswordReset, data.Token)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		status, apiError := HandleError(models.ErrInvalidUser)
+		c.JSON(status, apiError)
+		return
+	}
+
+	// check the password (policy, recent passwords) before the token is used up
+	// so the user can try another one with the same link
+	err = environment.Env.UserModel.ValidatePassword(userOID, data.NewPassword)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	// the token might have been used by a concurrent request in the meantime
+	_, err = authentication.ConsumeOneTimeToken(authentication.PurposePasswordReset, data.Token)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	err = environment.Env.UserModel.SetPassword(userOID, data.NewPassword)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	// whoever knew the old password is logged out
+	err = authentication.RevokeSessions(userID, "")
+	if err != nil {
+		fmt.Println(err) // ToDO: log
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEMail confirms the eMail-Address of an account using the token of a verification mail
+// guests become members
+func VerifyEMail(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	data := struct {
+		Token string `json:"token" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	userID, err := authentication.ConsumeOneTimeToken(authentication.PurposeEMailVerification, strings.TrimSpace(data.Token))
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	err = environment.Env.UserModel.VerifyEMail(userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerification sends a new verification mail to the logged-in user (eg. the first one got lost)
+func ResendVerification(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	dbUser, err := environment.Env.UserModel.GetUserByID(userID, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	if !dbUser.EMailVerified {
+		err = sendVerificationMail(userID, dbUser.LoginName, dbUser.EMailAddress)
+		if err != nil {
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// sends the link to confirm the eMail-Address of a new account
+func sendVerificationMail(userID string, userName string, eMailAddress string) error {
+
+	token, err := authentication.CreateOneTimeToken(authentication.PurposeEMailVerification, userID, authentication.EMailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := os.Getenv("CLIENT_URL") + "/verify?token=" + token
+
+	return environment.Env.Mailer.Send(mail.VerificationMessage(eMailAddress, userName, link))
+}
+
+// sends the link to set a new password, unknown addresses are ignored
+func sendPasswordResetMail(eMailAddress string) {
+
+	dbUser, err := environment.Env.UserModel.GetUserByEMail(eMailAddress)
+	if err != nil {
+		if err != models.ErrInvalidUser {
+			fmt.Println(err) // ToDO: log
+		}
+		return
+	}
+
+	token, err := authentication.CreateOneTimeToken(authentication.PurposePasswordReset, dbUser.ID.Hex(), authentication.PasswordResetTTL)
+	if err != nil {
+		fmt.Println(err) // ToDO: log
+		return
+	}
+
+	link := os.Getenv("CLIENT_URL") + "/reset-password?token=" + token
+
+	err = environment.Env.Mailer.Send(mail.PasswordResetMessage(dbUser.EMailAddress, dbUser.LoginName, link))
+	if err != nil {
+		fmt.Println(err) // ToDO: log
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"forza-garage/authentication"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions returns the devices the user is logged in with
+func ListSessions(c *gin.Context) {
+
+	au, err := authentication.ExtractTokenMetadata(authentication.AT, c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := authentication.FetchAuth(au)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := authentication.ListSessions(userID, au.SessionID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession logs the user out of a device
+// format => DELETE http://localhost:3000/user/sessions/<session id> or .../user/sessions/others (all but the current one)
+func RevokeSession(c *gin.Context) {
+
+	au, err := authentication.ExtractTokenMetadata(authentication.AT, c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := authentication.FetchAuth(au)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	if c.Param("id") == "others" {
+		err = authentication.RevokeSessions(userID, au.SessionID)
+	} else {
+		err = authentication.RevokeSession(userID, c.Param("id"))
+	}
+	if err != nil {
+		switch err {
+		case authentication.ErrSessionNotFound:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
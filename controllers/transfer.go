@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"forza-garage/authentication"
+	"forza-garage/environment"
+	"forza-garage/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportCourses creates courses from a JSON- or CSV-file (admins only)
+// format => POST http://localhost:3000/admin/courses/import?format=csv (body: file content, default json)
+func ImportCourses(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := models.ReadCourseTransfers(c.Request.Body, transferFormat(c))
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = err.Error()
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	// rows with errors are part of the result, they don't fail the request
+	result, err := environment.Env.CourseModel.ImportCourses(rows, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportCourses returns the courses matching the search filters in the import format (admins only)
+// format => http://localhost:3000/admin/courses/export?format=csv&game=0&series=2 (same filters as the course list)
+func ExportCourses(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	search, err := bindCourseSearch(c)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	rows, err := environment.Env.CourseModel.ExportCourses(search, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	format := transferFormat(c)
+	if format == models.TransferFormatCSV {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename=courses.csv")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename=courses.json")
+	}
+	c.Status(http.StatusOK)
+
+	err = models.WriteCourseTransfers(c.Writer, format, rows)
+	if err != nil {
+		// headers are already sent
+		c.Error(err)
+	}
+}
+
+// file format of import and export (json is the default)
+func transferFormat(c *gin.Context) string {
+	if c.Query("format") == models.TransferFormatCSV {
+		return models.TransferFormatCSV
+	}
+	return models.TransferFormatJSON
+}
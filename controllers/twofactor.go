@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"fmt"
+	"forza-garage/authentication"
+	"forza-garage/environment"
+	"forza-garage/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor is the second step of the log-in of users with two-factor authentication
+// the code of the app (or a recovery code) is sent along with the challenge returned by Login
+func LoginTwoFactor(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	data := struct {
+		Challenge string `json:"challenge" binding:"required"`
+		Code      string `json:"code" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	data.Challenge = strings.TrimSpace(data.Challenge)
+
+	// expired - the client needs to log-in again
+	userID, err := authentication.PeekOneTimeToken(authentication.PurposeLoginChallenge, data.Challenge)
+	if err != nil {
+		_, apiError = HandleError(err)
+		c.JSON(http.StatusUnauthorized, apiError)
+		return
+	}
+
+	// wrong codes are counted per user, a new challenge doesn't allow more guesses
+	err = authentication.CheckTwoFactorFailures(userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	err = environment.Env.UserModel.VerifyTwoFactor(userID, strings.TrimSpace(data.Code))
+	if err != nil {
+		if err == models.ErrTwoFactorCodeInvalid {
+			err = authentication.RecordTwoFactorFailure(userID)
+			if err != nil {
+				fmt.Println(err) // ToDO: log
+			}
+			_, apiError = HandleError(models.ErrTwoFactorCodeInvalid)
+			c.JSON(http.StatusUnauthorized, apiError)
+			return
+		}
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	// a challenge can only be completed once
+	_, err = authentication.ConsumeOneTimeToken(authentication.PurposeLoginChallenge, data.Challenge)
+	if err != nil {
+		_, apiError = HandleError(err)
+		c.JSON(http.StatusUnauthorized, apiError)
+		return
+	}
+
+	err = authentication.ResetTwoFactorFailures(userID)
+	if err != nil {
+		fmt.Println(err) // ToDO: log
+	}
+
+	dbUser, err := environment.Env.UserModel.GetUserByID(userID, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	startSession(c, dbUser)
+}
+
+// SetupTwoFactor creates the secret to be added to an authenticator app (QR code)
+// it becomes active by EnableTwoFactor
+func SetupTwoFactor(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	setup, err := environment.Env.UserModel.SetupTwoFactor(userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableTwoFactor activates two-factor authentication using a code of the app
+// the recovery codes are only returned here (and by RegenerateRecoveryCodes)
+func EnableTwoFactor(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	code, ok := bindTwoFactorCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := environment.Env.UserModel.EnableTwoFactor(userID, code)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodes{codes})
+}
+
+// DisableTwoFactor turns off two-factor authentication using a code of the app or a recovery code
+func DisableTwoFactor(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	code, ok := bindTwoFactorCode(c)
+	if !ok {
+		return
+	}
+
+	err = environment.Env.UserModel.DisableTwoFactor(userID, code)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes using a code of the app
+func RegenerateRecoveryCodes(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	code, ok := bindTwoFactorCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := environment.Env.UserModel.RegenerateRecoveryCodes(userID, code)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodes{codes})
+}
+
+// ResetTwoFactor turns off two-factor authentication of a user who lost access to the app (admins only)
+// format => DELETE http://localhost:3000/users/<user id>/2fa
+func ResetTwoFactor(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	err = environment.Env.UserModel.ResetTwoFactor(userID, c.Param("id"))
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// response of the functions creating recovery codes
+type recoveryCodes struct {
+	Codes []string `json:"recoveryCodes"`
+}
+
+// reads the code of the request body, sends the error response if there's none
+func bindTwoFactorCode(c *gin.Context) (string, bool) {
+
+	var apiError ErrorResponse
+
+	data := struct {
+		Code string `json:"code" binding:"required"`
+	}{}
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return "", false
+	}
+
+	return strings.TrimSpace(data.Code), true
+}
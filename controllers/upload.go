@@ -172,3 +172,46 @@ func DeleteFile(c *gin.Context) {
 
 	// always return OK since any error is ignored
 }
+
+// ModerateFile approves or rejects an uploaded file awaiting moderation (admins only)
+// the uploader is notified about the decision
+func ModerateFile(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// anonymous struct used to receive input (POST BODY)
+	data := struct {
+		ProfileID string `json:"profileID" binding:"required"`
+		FileName  string `json:"fileName" binding:"required"` // as part of the URL returned with the profile
+		Approve   bool   `json:"approve"`
+	}{}
+
+	// use 'shouldBind' so we can send customized messages
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = environment.Env.UploadModel.ModerateUpload(data.ProfileID, data.FileName, data.Approve, userID)
+	if err != nil {
+		switch err {
+		// file not found or not awaiting moderation
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -6,11 +6,12 @@ import (
 	"forza-garage/authentication"
 	"forza-garage/environment"
 	"forza-garage/helpers"
-	"forza-garage/lookups"
 	"forza-garage/models"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/twinj/uuid"
@@ -55,34 +56,19 @@ func Test(c *gin.Context) {
 	c.JSON(http.StatusOK, &user)
 }
 
-// GetUser sends a profile
+// GetUser sends the public profile of a user
+// works for guests and logged-in users
 func GetUser(c *gin.Context) {
 
-	/*
-		var apiError ErrorResponse
-		apiError.Code = InvalidJSON
-		apiError.Message = apiError.String(apiError.Code)
-		c.JSON(http.StatusUnprocessableEntity, apiError)
-		return*
-	*/
-	/*
-		c.Status(http.StatusInternalServerError)
-		return
-	*/
-
-	// used to apply privacy rules if someone else is viewing the profile
-	userID, err := authentication.Authenticate(c.Request)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
-		return
-	}
+	// guests have no token, the profile is the same for everyone
+	userID, _ := authentication.Authenticate(c.Request)
 
 	// fehlender parameter muss nicht geprüft werden, sonst wär's eine andere route
-	user, err := environment.Env.UserModel.GetUserByID(userID, c.Param("id"))
+	profile, err := environment.Env.UserModel.GetUserProfile(userID, c.Param("id"))
 	if err != nil {
 		// nothing found (not an error to the client)
 		if err == apperror.ErrNoData {
-			c.Status(http.StatusNoContent)
+			c.Status(http.StatusNotFound)
 			return
 		}
 		// technical errors
@@ -91,15 +77,33 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
-	// apply privacy rules to visitors
-	// ToDo: im model machen (effective/executive id)
-	if userID != c.Param("id") {
-		switch user.PrivacyCode {
-		case lookups.PrivacyUserName:
-			user.XBoxTag = ""
-		case lookups.PrivacyXboxTag:
-			user.LoginName = ""
-		}
+	// add patch to build URL of profile picture
+	if profile.ProfilePicture != nil {
+		profile.ProfilePicture.URL = os.Getenv("API_HOME") + ":" + os.Getenv("API_PORT") + environment.UploadEndpoint + "/" + profile.ProfilePicture.URL
+	}
+
+	c.JSON(http.StatusOK, &profile)
+
+	// log this request, if it was a new one
+	if environment.Env.Requests.Continue(getIP(c.Request), c.Param("id")) {
+		environment.Env.Tracker.SaveVisitor("user", c.Param("id"), userID)
+	}
+}
+
+// GetAccount sends the complete account of the current user (own data only)
+func GetAccount(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := environment.Env.UserModel.GetUserByID(userID, userID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
 	}
 
 	// don't send password hash
@@ -111,11 +115,6 @@ func GetUser(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, &user)
-
-	// log this request, if it was a new one
-	if environment.Env.Requests.Continue(getIP(c.Request), c.Param("id")) {
-		environment.Env.Tracker.SaveVisitor("user", c.Param("id"), userID)
-	}
 }
 
 // BlockUser adds someone to the user's ignorelist
@@ -182,9 +181,172 @@ func UnblockUser(c *gin.Context) { // ToDo: Unlock
 	}
 }
 
-// GetFriends sends a profile
+// GetSuggestions returns users the user may know (mutual friends, shared followings and courses)
+// format => http://localhost:3000/user/suggestions?limit=10
+func GetSuggestions(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	limit := 0
+	if c.Query("limit") != "" {
+		limit, err = strconv.Atoi(c.Query("limit"))
+		if err != nil {
+			apiError.Code = InvalidJSON
+			apiError.Message = apiError.String(apiError.Code)
+			c.JSON(http.StatusUnprocessableEntity, apiError)
+			return
+		}
+	}
+
+	suggestions, err := environment.Env.UserModel.GetSuggestions(userID, limit)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// GetBlockedUsers lists the users on the user's ignorelist
+func GetBlockedUsers(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	blocked, err := environment.Env.UserModel.GetBlockedUsers(userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, &blocked)
+}
+
+// GetObservings lists the courses etc. the user is observing
+func GetObservings(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	observings, err := environment.Env.UserModel.GetObservings(userID)
+	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.JSON(http.StatusOK, &observings)
+}
+
+// ObserveCourse adds a course to the user's watchlist
+func ObserveCourse(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// anonymous struct used to receive input (POST BODY)
+	data := struct {
+		CourseID string `json:"courseID" binding:"required"`
+	}{}
+
+	// use 'shouldBind' so we can send customized messages
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = environment.Env.CourseModel.ObserveCourse(data.CourseID, userID)
+	if err != nil {
+		switch err {
+		// course not found
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+}
+
+// UnobserveCourse removes a course from the user's watchlist
+func UnobserveCourse(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// anonymous struct used to receive input (POST BODY)
+	data := struct {
+		CourseID string `json:"courseID" binding:"required"`
+	}{}
+
+	// use 'shouldBind' so we can send customized messages
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	// the course itself is not checked, it might have been deleted in the meantime
+	err = environment.Env.UserModel.RemoveObserver(userID, data.CourseID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+}
+
+// GetFriends returns a page of a user's friends
+// format => http://localhost:3000/users/:id/friends?search=ab&cursor=...&limit=20
 func GetFriends(c *gin.Context) {
 
+	var apiError ErrorResponse
+
 	// userID (currentUser) could be used to check a user's permission to view another profile
 	/*
 		userID, err := authentication.Authenticate(c.Request)
@@ -194,8 +356,16 @@ func GetFriends(c *gin.Context) {
 		}
 	*/
 
+	specs, err := bindSocialList(c)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
 	// fehlender parameter muss nicht geprüft werden, sonst wär's eine andere route
-	friends, err := environment.Env.UserModel.GetFriends(c.Param("id"))
+	friends, err := environment.Env.UserModel.ListFriends(c.Param("id"), specs)
 	if err != nil {
 		// nothing found (not an error to the client)
 		if err == apperror.ErrNoData {
@@ -208,12 +378,15 @@ func GetFriends(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, &friends)
+	c.JSON(http.StatusOK, friends)
 }
 
-// GetFollowings lists all people someone's following
+// GetFollowings lists the people someone's following (paged)
+// format => http://localhost:3000/users/:id/followings?search=ab&cursor=...&limit=20
 func GetFollowings(c *gin.Context) {
 
+	var apiError ErrorResponse
+
 	// userID (currentUser) could be used to check a user's permission to view another profile
 	/*
 		userID, err := authentication.Authenticate(c.Request)
@@ -223,8 +396,16 @@ func GetFollowings(c *gin.Context) {
 		}
 	*/
 
+	specs, err := bindSocialList(c)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
 	// fehlender parameter muss nicht geprüft werden, sonst wär's eine andere route
-	friends, err := environment.Env.UserModel.GetFollowings(c.Param("id"))
+	followings, err := environment.Env.UserModel.ListFollowings(c.Param("id"), specs)
 	if err != nil {
 		// nothing found (not an error to the client)
 		if err == apperror.ErrNoData {
@@ -237,12 +418,15 @@ func GetFollowings(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, &friends)
+	c.JSON(http.StatusOK, followings)
 }
 
-// GetFollowers lists all people who are following someone
+// GetFollowers lists the people who are following someone (paged)
+// format => http://localhost:3000/users/:id/followers?search=ab&cursor=...&limit=20
 func GetFollowers(c *gin.Context) {
 
+	var apiError ErrorResponse
+
 	// userID (currentUser) could be used to check a user's permission to view another profile
 	/*
 		userID, err := authentication.Authenticate(c.Request)
@@ -252,8 +436,16 @@ func GetFollowers(c *gin.Context) {
 		}
 	*/
 
+	specs, err := bindSocialList(c)
+	if err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
 	// fehlender parameter muss nicht geprüft werden, sonst wär's eine andere route
-	followers, err := environment.Env.UserModel.GetFollowers(c.Param("id"))
+	followers, err := environment.Env.UserModel.ListFollowers(c.Param("id"), specs)
 	if err != nil {
 		// nothing found (not an error to the client)
 		if err == apperror.ErrNoData {
@@ -266,7 +458,25 @@ func GetFollowers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, &followers)
+	c.JSON(http.StatusOK, followers)
+}
+
+// reads the paging and search parameters of the social lists
+func bindSocialList(c *gin.Context) (models.SocialListSpecs, error) {
+	specs := models.SocialListSpecs{
+		Search: strings.TrimSpace(c.Query("search")),
+		Cursor: c.Query("cursor"),
+	}
+
+	if c.Query("limit") != "" {
+		limit, err := strconv.Atoi(c.Query("limit"))
+		if err != nil {
+			return specs, err
+		}
+		specs.Limit = limit
+	}
+
+	return specs, nil
 }
 
 // AddFriend adds someone to the user's friendlist
@@ -293,15 +503,103 @@ func AddFriend(c *gin.Context) {
 		return
 	}
 
-	err = environment.Env.UserModel.AddFriend(userID, data.FriendID)
+	friends, err := environment.Env.UserModel.AddFriend(userID, data.FriendID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	// false: the request is pending until the other user accepts it
+	res := struct {
+		Friends bool `json:"friends"`
+	}{friends}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// GetFriendRequests lists the user's pending friend requests
+// format => http://localhost:3000/user/friendRequests?direction=in|out (default in)
+func GetFriendRequests(c *gin.Context) {
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	requests, err := environment.Env.UserModel.GetFriendRequests(userID, c.Query("direction") != "out")
 	if err != nil {
+		// nothing found (not an error to the client)
+		if err == apperror.ErrNoData {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		// technical errors
 		status, apiError := HandleError(err)
 		c.JSON(status, apiError)
 		return
 	}
+
+	c.JSON(http.StatusOK, &requests)
+}
+
+// AcceptFriendRequest makes the requesting user a friend
+func AcceptFriendRequest(c *gin.Context) {
+	answerFriendRequest(c, environment.Env.UserModel.AcceptFriendRequest)
+}
+
+// DeclineFriendRequest rejects a friend request
+func DeclineFriendRequest(c *gin.Context) {
+	answerFriendRequest(c, environment.Env.UserModel.DeclineFriendRequest)
+}
+
+// CancelFriendRequest withdraws a friend request of the user
+func CancelFriendRequest(c *gin.Context) {
+	answerFriendRequest(c, environment.Env.UserModel.CancelFriendRequest)
+}
+
+// common handling of the friend request actions, the other user is passed in the body
+func answerFriendRequest(c *gin.Context, action func(userID string, otherUserID string) error) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// anonymous struct used to receive input (POST BODY)
+	data := struct {
+		UserID string `json:"userID" binding:"required"` // requesting or requested user
+	}{}
+
+	// use 'shouldBind' so we can send customized messages
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = action(userID, data.UserID)
+	if err != nil {
+		switch err {
+		// no such request
+		case apperror.ErrNoData:
+			c.Status(http.StatusNotFound)
+		default:
+			status, apiError := HandleError(err)
+			c.JSON(status, apiError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
-// RemoveFriend adds someone to the user's friendlist
+// RemoveFriend removes someone from the user's friendlist (also if they're no friends)
 func RemoveFriend(c *gin.Context) {
 
 	var apiError ErrorResponse
@@ -334,9 +632,11 @@ func RemoveFriend(c *gin.Context) {
 		c.JSON(status, apiError)
 		return
 	}
+
+	c.Status(http.StatusNoContent)
 }
 
-// FollowUser adds someone to the user's friendlist
+// FollowUser adds someone to the user's followings (following twice is not an error)
 func FollowUser(c *gin.Context) {
 
 	var apiError ErrorResponse
@@ -367,6 +667,42 @@ func FollowUser(c *gin.Context) {
 		c.JSON(status, apiError)
 		return
 	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnfollowUser removes someone from the user's followings (also if not followed)
+func UnfollowUser(c *gin.Context) {
+
+	var apiError ErrorResponse
+
+	userID, err := authentication.Authenticate(c.Request)
+	if err != nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// anonymous struct used to receive input (POST BODY)
+	data := struct {
+		UserID string `json:"userID" binding:"required"` // user to be unfollowed
+	}{}
+
+	// use 'shouldBind' so we can send customized messages
+	if err := c.ShouldBindJSON(&data); err != nil {
+		apiError.Code = InvalidJSON
+		apiError.Message = apiError.String(apiError.Code)
+		c.JSON(http.StatusUnprocessableEntity, apiError)
+		return
+	}
+
+	err = environment.Env.UserModel.UnfollowUser(userID, data.UserID)
+	if err != nil {
+		status, apiError := HandleError(err)
+		c.JSON(status, apiError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 // UploadProfilePicture sets the profile picture
@@ -53,6 +53,8 @@ func CastVote(c *gin.Context) {
 	switch data.ProfileType {
 	case "course":
 		profileVotes, err = environment.Env.VoteModel.CastVote(data, environment.Env.CourseModel.SetRating)
+	case "championship":
+		profileVotes, err = environment.Env.VoteModel.CastVote(data, environment.Env.ChampionshipModel.SetRating)
 	case "comment":
 		profileVotes, err = environment.Env.VoteModel.CastVote(data, environment.Env.CommentModel.SetRating)
 	default:
@@ -5,6 +5,8 @@ import (
 	"forza-garage/authorization"
 	"forza-garage/client"
 	"forza-garage/database"
+	"forza-garage/events"
+	"forza-garage/mail"
 	"forza-garage/models"
 	"os"
 
@@ -18,14 +20,18 @@ const UploadEndpoint = "/upload"
 
 // Environment is used for dependency-injection (package de-coupling)
 type Environment struct {
-	Requests     *client.Registry
-	Tracker      *analytics.Tracker
-	Credentials  *authorization.Credentials
-	UserModel    models.UserModel
-	VoteModel    models.VoteModel
-	CommentModel models.CommentModel
-	UploadModel  models.UploadModel
-	CourseModel  models.CourseModel
+	Requests          *client.Registry
+	Events            events.Broker
+	Mailer            mail.Mailer
+	Tracker           *analytics.Tracker
+	Credentials       *authorization.Credentials
+	UserModel         models.UserModel
+	VoteModel         models.VoteModel
+	CommentModel      models.CommentModel
+	UploadModel       models.UploadModel
+	CourseModel       models.CourseModel
+	ChampionshipModel models.ChampionshipModel
+	NotificationModel models.NotificationModel
 }
 
 // newEnv operates as the constructor to initialize the collection references (private)
@@ -61,6 +67,16 @@ func newEnv(mongoClient *mongo.Client, influxClient *influxdb2.Client) *Environm
 	// no deletes required for search bucket (TTL set)
 	env.Tracker.Requests = env.Requests
 
+	// live updates (server-sent events) - single API instance for now
+	env.Events = events.NewLocalBroker()
+
+	// e-mails (verification, password reset) - written to a directory or the log unless a mail server is used
+	if os.Getenv("MAIL_MODE") == "SMTP" {
+		env.Mailer = mail.NewSMTPMailer(os.Getenv("MAIL_HOST"), os.Getenv("MAIL_PORT"), os.Getenv("MAIL_USER"), os.Getenv("MAIL_PASS"), os.Getenv("MAIL_FROM"))
+	} else {
+		env.Mailer = mail.NewLogMailer(os.Getenv("MAIL_DIR"))
+	}
+
 	env.Credentials = new(authorization.Credentials)
 	env.Credentials.SetConnections(mongoCollections)
 
@@ -72,30 +88,72 @@ func newEnv(mongoClient *mongo.Client, influxClient *influxdb2.Client) *Environm
 	env.UserModel.Client = mongoClient
 	env.UserModel.Collection = mongoClient.Database(os.Getenv("DB_NAME")).Collection("users") // ToDO: Const
 	env.UserModel.Social = mongoClient.Database(os.Getenv("DB_NAME")).Collection("social")    // ToDO: Const
+	env.UserModel.PasswordPolicy = models.LoadPasswordPolicy()
 	env.UserModel.GetProfilePicture = env.UploadModel.GetMetaData
 
 	env.UploadModel.GetUserNameOID = env.UserModel.GetUserNameOID // ToDo: Evtl. auch in author - REIHENFOLGE heikel
 
+	// notifications (watchlist) - requires the user model, used by the profile models below
+	env.NotificationModel.Collection = mongoClient.Database(os.Getenv("DB_NAME")).Collection("notifications")
+	env.NotificationModel.GetObservers = env.UserModel.GetObservers
+	env.NotificationModel.Publish = env.Events.Publish
+	env.NotificationModel.IsBlocked = env.UserModel.IsBlocked
+
+	env.UploadModel.NotifyObservers = env.NotificationModel.NotifyObservers
+	env.UploadModel.Notify = env.NotificationModel.Notify
+	env.UserModel.Notify = env.NotificationModel.Notify
+
 	// inject user model function to analytics tracker after its initialization
 	env.Tracker.GetUserName = env.UserModel.GetUserName
 	// env.Tracker.GetUserNameOID = env.UserModel.GetUserNameOID - nicht mehr benötigt; alte Lösung
 
 	env.VoteModel.Collection = mongoClient.Database(os.Getenv("DB_NAME")).Collection("votes") // ToDO: Const
 	env.VoteModel.GetUserNameOID = env.UserModel.GetUserNameOID
+	env.VoteModel.Publish = env.Events.Publish
 
 	env.CommentModel.Collection = mongoClient.Database(os.Getenv("DB_NAME")).Collection("comments")
 	env.CommentModel.GetUserNameOID = env.UserModel.GetUserNameOID
 	env.CommentModel.GetUserVotes = env.VoteModel.GetUserVotes
+	env.CommentModel.GetCredentials = env.UserModel.GetCredentials
+	env.CommentModel.DeleteVotes = env.VoteModel.DeleteVotes
+	env.CommentModel.NotifyObservers = env.NotificationModel.NotifyObservers
+	env.CommentModel.Notify = env.NotificationModel.Notify
+	env.CommentModel.IsBlocked = env.UserModel.IsBlocked
+	env.CommentModel.GetBlockedIDs = env.UserModel.GetBlockedIDs
+
+	// suggestions - requires the vote and comment models
+	env.UserModel.GetCoVoters = env.VoteModel.GetCoVoters
+	env.UserModel.GetCoCommenters = env.CommentModel.GetCoCommenters
 
 	env.CourseModel.Client = mongoClient
 	env.CourseModel.Collection = mongoClient.Database(os.Getenv("DB_NAME")).Collection("racing") // ToDO: Const
+	env.CourseModel.Revisions = mongoClient.Database(os.Getenv("DB_NAME")).Collection("course_revisions")
 	// Funktionen aus dem User Model in's Course model "injecten"
 	env.CourseModel.GetUserName = env.UserModel.GetUserName
-	env.CourseModel.CredentialsReader = env.UserModel.GetCredentials // ToDo: auf authorization umstellen
+	env.CourseModel.CredentialsReader = env.Credentials.GetCredentials
 	env.CourseModel.GetUserVote = env.VoteModel.GetUserVote
+	// clean-up of dependent data (course deletion)
+	env.CourseModel.DeleteComments = env.CommentModel.DeleteComments
+	env.CourseModel.DeleteVotes = env.VoteModel.DeleteVotes
+	env.CourseModel.DeleteUploads = env.UploadModel.DeleteUploads
+	env.CourseModel.RemoveObservers = env.UserModel.RemoveObservers
+	env.CourseModel.RemoveNotifications = env.NotificationModel.RemoveNotifications
+	// watchlist
+	env.CourseModel.AddObserver = env.UserModel.AddObserver
+	env.CourseModel.NotifyObservers = env.NotificationModel.NotifyObservers
+	env.CourseModel.Notify = env.NotificationModel.Notify
+
+	// public profile - requires the course model
+	env.UserModel.GetPublicCourses = env.CourseModel.GetPublicCourses
 	// inject analytics
 	// env.CourseModel.Tracker = env.Tracker
 
+	env.ChampionshipModel.Client = mongoClient
+	env.ChampionshipModel.Collection = mongoClient.Database(os.Getenv("DB_NAME")).Collection("racing") // shared with courses
+	env.ChampionshipModel.GetUserName = env.UserModel.GetUserName
+	env.ChampionshipModel.CredentialsReader = env.Credentials.GetCredentials
+	env.ChampionshipModel.GetUserVote = env.VoteModel.GetUserVote
+
 	return env
 }
 
@@ -0,0 +1,36 @@
+package events
+
+// live updates pushed to the clients (server-sent events)
+
+// event names (sent as the SSE "event" field)
+const (
+	EventNotification = "notification" // a new notification of the user
+	EventVotes        = "votes"        // new vote counts of a profile
+)
+
+// Event is a message delivered to the subscribers of a topic
+type Event struct {
+	Name string      // event name, see above
+	Data interface{} // sent as JSON
+}
+
+// Broker distributes events to the subscribers of a topic
+// the local implementation serves a single API instance, a shared one (eg. redis pub/sub)
+// may be used later on without changing the publishers
+type Broker interface {
+	// Publish sends an event to all current subscribers of a topic (fire and forget)
+	Publish(topic string, event Event)
+	// Subscribe returns a channel receiving the events of the given topics
+	// the returned function must be called to end the subscription
+	Subscribe(topics []string) (<-chan Event, func())
+}
+
+// UserTopic is the topic of events concerning a user (eg. notifications)
+func UserTopic(userID string) string {
+	return "user:" + userID
+}
+
+// ProfileTopic is the topic of events concerning a profile (eg. a course's votes)
+func ProfileTopic(profileID string) string {
+	return "profile:" + profileID
+}
@@ -0,0 +1,64 @@
+package events
+
+import "sync"
+
+// buffered events per subscriber, further events are dropped for slow clients
+const subscriberBuffer = 16
+
+// LocalBroker is an in-process broker (events are not shared between API instances)
+type LocalBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewLocalBroker creates an empty broker
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+// Publish sends an event to all current subscribers of a topic
+// it never blocks the publisher
+func (b *LocalBroker) Publish(topic string, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- event:
+		default:
+			// subscriber is not reading fast enough
+		}
+	}
+}
+
+// Subscribe registers a channel for the given topics
+func (b *LocalBroker) Subscribe(topics []string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	for _, t := range topics {
+		if b.subscribers[t] == nil {
+			b.subscribers[t] = make(map[chan Event]struct{})
+		}
+		b.subscribers[t][ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			for _, t := range topics {
+				delete(b.subscribers[t], ch)
+				if len(b.subscribers[t]) == 0 {
+					delete(b.subscribers, t)
+				}
+			}
+			// no more sends possible since the publishers hold the lock
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
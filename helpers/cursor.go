@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// EncodeCursor converts the sort key of the last item of a page into an opaque token
+// the client passes that token back unchanged to receive the next page
+func EncodeCursor(v interface{}) (string, error) {
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor reads a token created by EncodeCursor into the given struct
+func DecodeCursor(token string, v interface{}) error {
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
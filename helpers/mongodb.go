@@ -44,3 +44,13 @@ func DocumentExists(collection *mongo.Collection, id primitive.ObjectID) (bool,
 	return true, nil
 }
 */
+
+// ContainsObjectID checks if a slice of ObjectIDs contains a given ID
+func ContainsObjectID(slice []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, item := range slice {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogMailer doesn't send messages, they're written to a directory (one file per message)
+// or to the log if no directory is set - for local development and tests
+type LogMailer struct {
+	dir string
+}
+
+// NewLogMailer creates a mailer writing to the given directory (empty = log)
+func NewLogMailer(dir string) *LogMailer {
+	return &LogMailer{dir: dir}
+}
+
+// Send writes a message
+func (m *LogMailer) Send(msg Message) error {
+
+	text := "To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"\r\n" +
+		msg.Body
+
+	if m.dir == "" {
+		log.Printf("mail: %s", text)
+		return nil
+	}
+
+	err := os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	// eg. 20060102-150405.000000000_user@example.com.eml
+	name := fmt.Sprintf("%s_%s.eml",
+		time.Now().Format("20060102-150405.000000000"),
+		strings.NewReplacer("/", "_", "\\", "_").Replace(msg.To))
+
+	return ioutil.WriteFile(filepath.Join(m.dir, name), []byte(text), 0644)
+}
@@ -0,0 +1,40 @@
+package mail
+
+// e-mails sent to the users (account verification, password reset)
+
+// Message is a plain text e-mail
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+// the SMTP implementation is used in production, the log implementation for local development and tests
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// VerificationMessage asks a new user to confirm the e-mail address
+func VerificationMessage(to string, userName string, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "forza-garage.net - please verify your e-mail address",
+		Body: "Hi " + userName + "\r\n\r\n" +
+			"please confirm your e-mail address by opening the following link:\r\n\r\n" +
+			link + "\r\n\r\n" +
+			"If you did not create an account, you can ignore this message.\r\n",
+	}
+}
+
+// PasswordResetMessage sends the link to set a new password
+func PasswordResetMessage(to string, userName string, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "forza-garage.net - reset your password",
+		Body: "Hi " + userName + "\r\n\r\n" +
+			"a new password was requested for your account. Open the following link to set it:\r\n\r\n" +
+			link + "\r\n\r\n" +
+			"If you did not request a new password, you can ignore this message.\r\n",
+	}
+}
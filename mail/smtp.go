@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends messages through a mail server
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a mailer for the given server, no authentication is used if the user is empty
+func NewSMTPMailer(host string, port string, user string, password string, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if user != "" {
+		m.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return m
+}
+
+// Send delivers a message (STARTTLS is used if the server supports it)
+func (m *SMTPMailer) Send(msg Message) error {
+
+	// header values must not contain line breaks (header injection)
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	header := "From: " + m.from + "\r\n" +
+		"To: " + clean.Replace(msg.To) + "\r\n" +
+		"Subject: " + clean.Replace(msg.Subject) + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n"
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{clean.Replace(msg.To)}, []byte(header+msg.Body))
+}
@@ -56,6 +56,25 @@ func main() {
 	// Inject DB-Connections to models
 	environment.InitializeModels()
 
+	// indexes which are required by queries (eg. text search)
+	err = environment.Env.CourseModel.EnsureIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = environment.Env.NotificationModel.EnsureIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// command line mode, eg. "forza-garage import courses.csv <admin user ID>"
+	if len(os.Args) > 1 {
+		err = runCommand(os.Args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// we're keeping track of client requests to control certain endpoints
 	// hence we need to frequently shrink the list of recent requests
 	requestTicker := time.NewTicker(time.Duration(1 * time.Minute)) // 5 * time.Second
@@ -73,6 +92,22 @@ func main() {
 		}
 	}()
 
+	// deleted courses and comments are kept in the trash bin for a while (TRASH_RETENTION_DAYS)
+	// afterwards they're removed for good
+	purgeTicker := time.NewTicker(time.Duration(1 * time.Hour))
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-purgeTicker.C:
+				environment.Env.CourseModel.PurgeCourses()
+				environment.Env.CommentModel.PurgeComments()
+			}
+		}
+	}()
+
 	// ToDo: Repl Influx->Mongo eher Batch-mässig; File-Check?
 	/*
 		// replicate profile visit log from cache to db
@@ -113,6 +148,7 @@ func main() {
 	environment.Env.Tracker.SearchAPI.WriteAPI.Flush()
 
 	requestTicker.Stop()
+	purgeTicker.Stop()
 	// replTicker.Stop()
 	done <- true
 
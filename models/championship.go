@@ -0,0 +1,444 @@
+package models
+
+import (
+	"context"
+	"forza-garage/apperror"
+	"forza-garage/authorization"
+	"forza-garage/database"
+	"forza-garage/helpers"
+	"forza-garage/lookups"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Championship (CMP) is the "interface" used for client communication
+// championships are stored in the same collection as courses ("racing")
+type Championship struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	MetaInfo       Header             `json:"metaInfo" bson:"metaInfo"` // non-ptr = always present
+	VisibilityCode int32              `json:"visibilityCode" bson:"visibilityCD"`
+	VisibilityText string             `json:"visibilityText" bson:"-"`
+	GameCode       int32              `json:"gameCode" bson:"gameCD"`
+	GameText       string             `json:"gameText" bson:"-"`
+	Name           string             `json:"name" bson:"name"` // same name as courses to enables over-all searches
+	SeriesCode     int32              `json:"seriesCode" bson:"seriesCD"`
+	SeriesText     string             `json:"seriesText" bson:"-"`
+	CarClasses     []Lookup           `json:"carClassCodes" bson:"carClasses"` // allowed car classes (restrictions)
+	Description    string             `json:"description" bson:"description,omitempty"`
+	Races          []CourseRef        `json:"races" bson:"races"` // ordered line-up; identifies object type (for searches, $exists)
+	Tags           []string           `json:"tags" bson:"tags,omitempty"`
+}
+
+// ChampionshipListItem is the reduced/simplified model used for listings
+type ChampionshipListItem struct {
+	ID          primitive.ObjectID `json:"id"`
+	CreatedTS   time.Time          `json:"createdTS"`
+	CreatedID   primitive.ObjectID `json:"createdID"`
+	CreatedName string             `json:"createdName"`
+	Rating      float32            `json:"rating"`
+	GameCode    int32              `json:"gameCode"`
+	GameText    string             `json:"gameText"`
+	Name        string             `json:"name"`
+	SeriesCode  int32              `json:"seriesCode"`
+	SeriesText  string             `json:"seriesText"`
+	CarClasses  []Lookup           `json:"carClasses"`
+	RaceCount   int                `json:"raceCount"`
+}
+
+// ChampionshipSearchParams is passed as the search params
+type ChampionshipSearchParams struct {
+	GameCode    int32
+	SeriesCodes []int32
+	SearchTerm  string
+}
+
+// ChampionshipModel provides the logic to the interface and access to the database
+type ChampionshipModel struct {
+	Client     *mongo.Client
+	Collection *mongo.Collection // same as courses
+	// Gewisse Informationen kommen vom User-Model, die werden hier referenziert
+	GetUserName       func(ID string) (string, error)
+	CredentialsReader func(userOID primitive.ObjectID, loadFriendlist bool) *authorization.Credentials
+	GetUserVote       func(profileID string, userID string) (int32, error) // injected from vote model
+}
+
+// Validate checks given values and sets defaults where applicable (immutable)
+func (m ChampionshipModel) Validate(championship Championship) (*Championship, error) {
+
+	cleaned := championship
+
+	cleaned.Name = strings.TrimSpace(cleaned.Name)
+	if cleaned.Name == "" {
+		return nil, ErrChampionshipNameMissing
+	}
+
+	if len(cleaned.Races) == 0 {
+		return nil, ErrChampionshipRacesMissing
+	}
+
+	return &cleaned, nil
+}
+
+// CreateChampionship adds a new championship - validated by controller
+func (m ChampionshipModel) CreateChampionship(championship *Championship, userID string) (string, error) {
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), true)
+
+	// the line-up must consist of existing courses of the same game the user is allowed to see
+	races, err := m.resolveRaces(championship.Races, championship.GameCode, credentials)
+	if err != nil {
+		return "", err
+	}
+	championship.Races = races
+
+	// set "system-fields"
+	championship.ID = primitive.NewObjectID()
+	championship.MetaInfo.CreatedID = helpers.ObjectID(userID)
+	userName, err := m.GetUserName(userID)
+	if err != nil {
+		// Fachlicher Fehler oder bereits wrapped
+		return "", err
+	}
+	championship.MetaInfo.CreatedName = userName
+	championship.MetaInfo.TouchedTS = time.Now()
+	championship.MetaInfo.Rating = 0
+	championship.MetaInfo.RecVer = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	res, err := m.Collection.InsertOne(ctx, championship)
+	if err != nil {
+		return "", helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// SearchChampionships lists or searches championships
+// ACHTUNG: Die Liste wird sortiert und limitiert, daher können einzelne Dokumente herausfallen ;-)
+func (m ChampionshipModel) SearchChampionships(searchSpecs *ChampionshipSearchParams, userID string) ([]ChampionshipListItem, error) {
+
+	fields := bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "metaInfo", Value: 1},
+		{Key: "gameCD", Value: 1},
+		{Key: "name", Value: 1},
+		{Key: "seriesCD", Value: 1},
+		{Key: "carClasses", Value: 1},
+		{Key: "races", Value: 1},
+	}
+
+	sort := bson.D{
+		{Key: "metaInfo.ratingSort", Value: -1},
+		{Key: "metaInfo.rating", Value: -1},
+		{Key: "metaInfo.touchedTS", Value: -1},
+	}
+
+	opts := options.Find().SetProjection(fields).SetLimit(20).SetSort(sort)
+
+	query := authorization.NewFilter().
+		Field("gameCD", searchSpecs.GameCode).
+		Field("races", bson.D{{Key: "$exists", Value: true}}). // selects championships rather than courses
+		Field("seriesCD", bson.D{{Key: "$in", Value: searchSpecs.SeriesCodes}})
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), true)
+	query.Visible(credentials, "metaInfo.createdID")
+
+	if searchSpecs.SearchTerm != "" {
+		// LIKE %searchTerm% (case-insensitive), meta characters are escaped
+		query.Field("name", primitive.Regex{Pattern: regexp.QuoteMeta(searchSpecs.SearchTerm), Options: "i"})
+	}
+
+	filter := query.Build()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var championships []Championship
+
+	err = cursor.All(ctx, &championships)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// check for empty result set (no error raised by find)
+	if championships == nil {
+		return nil, apperror.ErrNoData
+	}
+
+	// copy data to reduced list-struct
+	var championshipList []ChampionshipListItem
+	var championship ChampionshipListItem
+
+	for _, c := range championships {
+		championship.ID = c.ID
+		championship.CreatedTS = primitive.ObjectID.Timestamp(c.ID)
+		championship.CreatedID = c.MetaInfo.CreatedID
+		championship.CreatedName = c.MetaInfo.CreatedName
+		championship.Rating = c.MetaInfo.Rating
+		championship.GameCode = c.GameCode
+		championship.GameText = database.GetLookupText(lookups.LookupType(lookups.LTgame), c.GameCode)
+		championship.Name = c.Name
+		championship.SeriesCode = c.SeriesCode
+		championship.SeriesText = database.GetLookupText(lookups.LookupType(lookups.LTseries), c.SeriesCode)
+		championship.CarClasses = nil
+		if len(c.CarClasses) > 0 {
+			championship.CarClasses = make([]Lookup, len(c.CarClasses))
+			for i, v := range c.CarClasses {
+				championship.CarClasses[i].Value = v.Value
+				championship.CarClasses[i].Text = database.GetLookupText(lookups.LookupType(lookups.LTcarClass), v.Value)
+			}
+		}
+		championship.RaceCount = len(c.Races)
+
+		championshipList = append(championshipList, championship)
+	}
+
+	return championshipList, nil
+}
+
+// GetChampionship returns one
+func (m ChampionshipModel) GetChampionship(championshipID string, userID string) (*Championship, error) {
+
+	id, err := primitive.ObjectIDFromHex(championshipID)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
+
+	data := Championship{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "races", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(&data)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
+	// extract creation timestamp from OID
+	data.MetaInfo.CreatedTS = primitive.ObjectID(id).Timestamp()
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false).LookupFriendship()
+
+	err = authorization.GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
+	if err != nil {
+		// no wrapping needed, since function returns app errors
+		return nil, err
+	}
+
+	// get user's vote if present
+	if userID != "" {
+		// fehler kann hier ignoriert werden (default = 0 = note voted)
+		uv, _ := m.GetUserVote(championshipID, userID)
+		data.MetaInfo.UserVote = uv
+	}
+
+	m.addLookups(&data)
+
+	return &data, nil
+}
+
+// UpdateChampionship modifies a given championship
+func (m ChampionshipModel) UpdateChampionship(championship *Championship, userID string) error {
+
+	// read "metadata" to check permissions and perform optimistic locking
+	fields := bson.D{
+		{Key: "_id", Value: 0},
+		{Key: "metaInfo.createdID", Value: 1},
+		{Key: "metaInfo.recVer", Value: 1},
+		{Key: "visibilityCD", Value: 1},
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: championship.ID},
+		{Key: "races", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+
+	data := struct {
+		MetaInfo       Header `bson:"metaInfo"`
+		VisibilityCode int32  `bson:"visibilityCD"`
+	}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(fields)).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData // document might have been deleted
+		}
+		// pass any other error
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), true)
+
+	err = authorization.GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
+	if err != nil {
+		// no wrapping needed, since function returns app errors
+		return err
+	}
+
+	// optimistic lock check
+	if data.MetaInfo.RecVer != championship.MetaInfo.RecVer {
+		// document was changed by another user since last read
+		return apperror.ErrRecordChanged
+	}
+
+	races, err := m.resolveRaces(championship.Races, championship.GameCode, credentials)
+	if err != nil {
+		return err
+	}
+	championship.Races = races
+
+	// set "systemfields"
+	championship.MetaInfo.ModifiedID = credentials.UserID
+	championship.MetaInfo.ModifiedName = credentials.LoginName
+	championship.MetaInfo.ModifiedTS = time.Now()
+	championship.MetaInfo.TouchedTS = championship.MetaInfo.ModifiedTS
+
+	// set fields to be possibily updated
+	fields = bson.D{
+		// systemfields
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.modifiedTS", Value: championship.MetaInfo.ModifiedTS}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.modifiedID", Value: championship.MetaInfo.ModifiedID}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.modifiedName", Value: championship.MetaInfo.ModifiedName}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.touchedTS", Value: championship.MetaInfo.TouchedTS}}},
+		{Key: "$inc", Value: bson.D{{Key: "metaInfo.recVer", Value: 1}}}, // increase record version no
+		// payload
+		{Key: "$set", Value: bson.D{{Key: "visibilityCD", Value: championship.VisibilityCode}}},
+		{Key: "$set", Value: bson.D{{Key: "gameCD", Value: championship.GameCode}}},
+		{Key: "$set", Value: bson.D{{Key: "name", Value: championship.Name}}},
+		{Key: "$set", Value: bson.D{{Key: "seriesCD", Value: championship.SeriesCode}}},
+		{Key: "$set", Value: bson.D{{Key: "carClasses", Value: championship.CarClasses}}}, // arrays replaced as a whole
+		{Key: "$set", Value: bson.D{{Key: "description", Value: championship.Description}}},
+		{Key: "$set", Value: bson.D{{Key: "races", Value: championship.Races}}},
+		{Key: "$set", Value: bson.D{{Key: "tags", Value: championship.Tags}}},
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.ErrNoData // document might have been deleted
+	}
+
+	return nil
+}
+
+// SetRating is called by the voting model
+func (m ChampionshipModel) SetRating(social *Social) error {
+
+	fields := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.rating", Value: social.Rating}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.ratingSort", Value: social.SortOrder}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.upVotes", Value: social.UpVotes}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.downVotes", Value: social.DownVotes}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.touchedTS", Value: social.TouchedTS}}},
+	}
+
+	filter := bson.D{{Key: "_id", Value: social.ProfileOID}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.ErrNoData // document might have been deleted
+	}
+
+	return nil
+}
+
+// internal helpers (private methods)
+
+// resolveRaces checks the line-up and returns it with the current course names (order is kept)
+func (m ChampionshipModel) resolveRaces(races []CourseRef, gameCode int32, credentials *authorization.Credentials) ([]CourseRef, error) {
+
+	ids := make([]primitive.ObjectID, len(races))
+	for i, r := range races {
+		ids[i] = r.ID
+	}
+
+	fields := bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "name", Value: 1},
+		{Key: "gameCD", Value: 1},
+		{Key: "visibilityCD", Value: 1},
+		{Key: "metaInfo.createdID", Value: 1},
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}},
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$exists", Value: true}}},        // courses only
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // not in trash bin
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Collection.Find(ctx, filter, options.Find().SetProjection(fields))
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var courses []Course
+	err = cursor.All(ctx, &courses)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	found := make(map[primitive.ObjectID]Course, len(courses))
+	for _, c := range courses {
+		found[c.ID] = c
+	}
+
+	// a course may be used more than once in a championship
+	resolved := make([]CourseRef, len(races))
+	for i, r := range races {
+		c, ok := found[r.ID]
+		if !ok || c.GameCode != gameCode {
+			return nil, ErrChampionshipRaceInvalid
+		}
+		if authorization.GrantPermissions(c.VisibilityCode, c.MetaInfo.CreatedID, credentials) != nil {
+			return nil, ErrChampionshipRaceInvalid
+		}
+		resolved[i] = CourseRef{ID: c.ID, Name: c.Name}
+	}
+
+	return resolved, nil
+}
+
+// actually that's not immutable, but ok here
+func (m ChampionshipModel) addLookups(championship *Championship) *Championship {
+	championship.VisibilityText = database.GetLookupText(lookups.LookupType(lookups.LTvisibility), championship.VisibilityCode)
+	championship.GameText = database.GetLookupText(lookups.LookupType(lookups.LTgame), championship.GameCode)
+	championship.SeriesText = database.GetLookupText(lookups.LookupType(lookups.LTseries), championship.SeriesCode)
+	for i, v := range championship.CarClasses {
+		championship.CarClasses[i].Text = database.GetLookupText(lookups.LookupType(lookups.LTcarClass), v.Value)
+	}
+
+	return championship
+}
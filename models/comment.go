@@ -23,28 +23,31 @@ import (
 // Comment is the "interface" used for client communication
 // optimistic locking not required here
 type Comment struct {
-	ID           primitive.ObjectID `json:"id" bson:"_id"`                                      // comment or reply ID
-	ProfileID    primitive.ObjectID `json:"profileId,omitempty" bson:"profileId,omitempty"`     // required for comments
-	ProfileType  *string            `json:"profileType,omitempty" bson:"profileType,omitempty"` // required for comments
-	CreatedTS    time.Time          `json:"createdTS" bson:"-"`                                 // extracted from OID
-	CreatedID    primitive.ObjectID `json:"createdID" bson:"createdID"`
-	CreatedName  string             `json:"createdName" bson:"createdName"`
-	ModifiedTS   *time.Time         `json:"modifiedTS,omitempty" bson:"modifiedTS,omitempty"` // edited if present
-	ModifiedID   primitive.ObjectID `json:"modifiedID,omitempty" bson:"modifiedID,omitempty"` // maybe used to flag "edited by admin"
-	ModifiedName *string            `json:"modifiedName,omitempty" bson:"modifiedName,omitempty"`
-	UpVotes      int32              `json:"upVotes" bson:"upVotes"`
-	DownVotes    int32              `json:"downVotes" bson:"downVotes"`
-	UserVote     int32              `json:"userVote" bson:"-"`            // returned dynamically by API
-	Rating       float32            `json:"rating" bson:"rating"`         // calculated by the voting function & persisted
-	RatingSort   float32            `json:"ratingSort" bson:"ratingSort"` // calculated by the voting function & persisted (lowerBound)
-	StatusCode   int32              `json:"statusCode" bson:"statusCD"`
-	StatusText   string             `json:"statusText" bson:"-"`
-	StatusTS     time.Time          `json:"statusTS" bson:"statusTS"`
-	StatusID     primitive.ObjectID `json:"statusID" bson:"statusID"`
-	StatusName   string             `json:"statusName" bson:"statusName"`
-	Pinned       *bool              `json:"pinned,omitempty" bson:"pinned,omitempty"`
-	Comment      string             `json:"comment" bson:"comment"`
-	Replies      []Comment          `json:"replies,omitempty" bson:"replies,omitempty"` // applies to GET-requests only
+	ID           primitive.ObjectID  `json:"id" bson:"_id"`                                      // comment or reply ID
+	ProfileID    primitive.ObjectID  `json:"profileId,omitempty" bson:"profileId,omitempty"`     // required for comments
+	ProfileType  *string             `json:"profileType,omitempty" bson:"profileType,omitempty"` // required for comments
+	CreatedTS    time.Time           `json:"createdTS" bson:"-"`                                 // extracted from OID
+	CreatedID    primitive.ObjectID  `json:"createdID" bson:"createdID"`
+	CreatedName  string              `json:"createdName" bson:"createdName"`
+	ModifiedTS   *time.Time          `json:"modifiedTS,omitempty" bson:"modifiedTS,omitempty"` // edited if present
+	ModifiedID   primitive.ObjectID  `json:"modifiedID,omitempty" bson:"modifiedID,omitempty"` // maybe used to flag "edited by admin"
+	ModifiedName *string             `json:"modifiedName,omitempty" bson:"modifiedName,omitempty"`
+	UpVotes      int32               `json:"upVotes" bson:"upVotes"`
+	DownVotes    int32               `json:"downVotes" bson:"downVotes"`
+	UserVote     int32               `json:"userVote" bson:"-"`            // returned dynamically by API
+	Rating       float32             `json:"rating" bson:"rating"`         // calculated by the voting function & persisted
+	RatingSort   float32             `json:"ratingSort" bson:"ratingSort"` // calculated by the voting function & persisted (lowerBound)
+	StatusCode   int32               `json:"statusCode" bson:"statusCD"`
+	StatusText   string              `json:"statusText" bson:"-"`
+	StatusTS     time.Time           `json:"statusTS" bson:"statusTS"`
+	StatusID     primitive.ObjectID  `json:"statusID" bson:"statusID"`
+	StatusName   string              `json:"statusName" bson:"statusName"`
+	DeletedTS    *time.Time          `json:"deletedTS,omitempty" bson:"deletedTS,omitempty"` // moved to trash bin if present
+	DeletedID    *primitive.ObjectID `json:"deletedID,omitempty" bson:"deletedID,omitempty"`
+	DeletedName  *string             `json:"deletedName,omitempty" bson:"deletedName,omitempty"`
+	Pinned       *bool               `json:"pinned,omitempty" bson:"pinned,omitempty"`
+	Comment      string              `json:"comment" bson:"comment"`
+	Replies      []Comment           `json:"replies,omitempty" bson:"replies,omitempty"` // applies to GET-requests only
 }
 
 // CommentListItem is the reduced data structure used for lists (eg. comment sections of profiles)
@@ -71,6 +74,13 @@ type CommentModel struct {
 	GetUserNameOID func(userID primitive.ObjectID) (string, error)
 	GetCredentials func(userId string, loadFriendlist bool) *Credentials
 	GetUserVotes   func(domain string, userID string) ([]UserVote, error) // injected from votes model
+	DeleteVotes    func(profileOIDs []primitive.ObjectID) error           // injected from votes model
+	// observers of the commented profile
+	NotifyObservers func(profileOID primitive.ObjectID, notificationType string, actorOID primitive.ObjectID, actorName string) // injected from notification model
+	Notify          func(notification Notification)                                                                             // injected from notification model
+	// blocked users can't reply to the blocker and are hidden from them
+	IsBlocked     func(userOID primitive.ObjectID, blockedUserOID primitive.ObjectID) (bool, error) // injected from user model
+	GetBlockedIDs func(userOID primitive.ObjectID) ([]primitive.ObjectID, error)                    // injected from user model
 }
 
 // Validate checks given values and sets defaults where applicable (immutable)
@@ -116,6 +126,10 @@ func (m CommentModel) Create(comment *Comment) (string, error) {
 	comment.StatusID = comment.CreatedID
 	comment.StatusName = comment.CreatedName
 
+	comment.DeletedTS = nil
+	comment.DeletedID = nil
+	comment.DeletedName = nil
+
 	if comment.ID == primitive.NilObjectID {
 		// new comment
 		comment.ID = primitive.NewObjectID()
@@ -128,6 +142,8 @@ func (m CommentModel) Create(comment *Comment) (string, error) {
 			return "", helpers.WrapError(err, helpers.FuncName()) // primitive.NilObjectID.Hex() ? probly useless
 		}
 
+		m.notifyObservers(comment.ProfileID, comment)
+
 		return res.InsertedID.(primitive.ObjectID).Hex(), nil
 	} else {
 		// new reply - push array
@@ -141,8 +157,17 @@ func (m CommentModel) Create(comment *Comment) (string, error) {
 		comment.Pinned = nil // by convention, answers can't be pinged
 		comment.Replies = nil
 
+		// users blocked by the parent's author can't reply
+		err = m.checkBlocked(id, comment.CreatedID)
+		if err != nil {
+			return "", err
+		}
+
 		// ID set by controller
-		filter := bson.D{{Key: "_id", Value: id}}
+		filter := bson.D{
+			{Key: "_id", Value: id},
+			{Key: "deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // no replies to deleted comments
+		}
 		// insert new reply at the beginning of the array
 		fields := bson.D{
 			{Key: "$push", Value: bson.D{
@@ -153,16 +178,41 @@ func (m CommentModel) Create(comment *Comment) (string, error) {
 			}},
 		}
 
+		// the parent's profile and author are returned for the notifications
+		opts := options.FindOneAndUpdate().SetProjection(bson.D{
+			{Key: "profileId", Value: 1},
+			{Key: "profileType", Value: 1},
+			{Key: "createdID", Value: 1},
+		})
+
+		parent := struct {
+			ProfileID   primitive.ObjectID `bson:"profileId"`
+			ProfileType string             `bson:"profileType"`
+			CreatedID   primitive.ObjectID `bson:"createdID"`
+		}{}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel() // nach 10 Sekunden abbrechen
 
-		result, err := m.Collection.UpdateOne(ctx, filter, fields)
+		err := m.Collection.FindOneAndUpdate(ctx, filter, fields, opts).Decode(&parent)
 		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return "", apperror.ErrNoData // document might have been deleted
+			}
 			return "", helpers.WrapError(err, helpers.FuncName())
 		}
 
-		if result.MatchedCount == 0 {
-			return "", apperror.ErrNoData // document might have been deleted
+		m.notifyObservers(parent.ProfileID, comment)
+
+		if comment.StatusCode == lookups.CommentStatusVisible {
+			m.Notify(Notification{
+				UserID:      parent.CreatedID,
+				Type:        NotificationReply,
+				ProfileID:   parent.ProfileID,
+				ProfileType: parent.ProfileType,
+				ActorID:     comment.CreatedID,
+				ActorName:   comment.CreatedName,
+			})
 		}
 
 		return comment.ID.Hex(), nil
@@ -170,6 +220,44 @@ func (m CommentModel) Create(comment *Comment) (string, error) {
 
 }
 
+// checks if the author of a comment has blocked the replying user
+func (m CommentModel) checkBlocked(commentOID primitive.ObjectID, userOID primitive.ObjectID) error {
+
+	opts := options.FindOne().SetProjection(bson.D{{Key: "createdID", Value: 1}})
+
+	parent := struct {
+		CreatedID primitive.ObjectID `bson:"createdID"`
+	}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Collection.FindOne(ctx, bson.D{{Key: "_id", Value: commentOID}}, opts).Decode(&parent)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData
+		}
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	blocked, err := m.IsBlocked(parent.CreatedID, userOID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+
+	return nil
+}
+
+// comments awaiting moderation are not visible yet, so there is nothing to notify about
+func (m CommentModel) notifyObservers(profileOID primitive.ObjectID, comment *Comment) {
+	if comment.StatusCode == lookups.CommentStatusVisible {
+		m.NotifyObservers(profileOID, NotificationCommented, comment.CreatedID, comment.CreatedName)
+	}
+}
+
 // ListComments returns all comments and their possible answers to a given profile (limited)
 // userID is required to look-up the user's votes
 func (m CommentModel) ListComments(profileId string, userID string) ([]CommentListItem, error) {
@@ -202,6 +290,23 @@ func (m CommentModel) ListComments(profileId string, userID string) ([]CommentLi
 		{Key: "statusCD", Value: bson.D{
 			{Key: "$nin", Value: exclStatus},
 		}},
+		{Key: "deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // trash bin
+	}
+
+	// comments of blocked users are hidden from the blocker
+	var blockedIDs []primitive.ObjectID
+	if userID != "" {
+		userOID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, ErrInvalidUser
+		}
+		blockedIDs, err = m.GetBlockedIDs(userOID)
+		if err != nil {
+			return nil, err
+		}
+		if len(blockedIDs) > 0 {
+			filter = append(filter, bson.E{Key: "createdID", Value: bson.D{{Key: "$nin", Value: blockedIDs}}})
+		}
 	}
 
 	sort := bson.D{
@@ -246,20 +351,23 @@ func (m CommentModel) ListComments(profileId string, userID string) ([]CommentLi
 		comment.DownVotes = c.DownVotes
 		comment.Pinned = c.Pinned
 		comment.Comment = c.Comment
-		if len(c.Replies) > 0 {
-			comment.Replies = make([]CommentListItem, len(c.Replies))
-			for i, r := range c.Replies {
-				fmt.Println(r.UpVotes)
-				comment.Replies[i].ID = r.ID
-				comment.Replies[i].CreatedTS = primitive.ObjectID.Timestamp(r.ID)
-				comment.Replies[i].CreatedID = r.CreatedID
-				comment.Replies[i].CreatedName = r.CreatedName
-				comment.Replies[i].Modified = (r.ModifiedTS != nil)
-				comment.Replies[i].UpVotes = r.UpVotes
-				comment.Replies[i].DownVotes = r.DownVotes
-				comment.Replies[i].Pinned = nil // by convention not present for replies
-				comment.Replies[i].Comment = r.Comment
+		comment.Replies = nil
+		for _, r := range c.Replies {
+			// replies in the trash bin are filtered here (embedded)
+			if r.DeletedTS != nil || helpers.ContainsObjectID(blockedIDs, r.CreatedID) {
+				continue
 			}
+			comment.Replies = append(comment.Replies, CommentListItem{
+				ID:          r.ID,
+				CreatedTS:   primitive.ObjectID.Timestamp(r.ID),
+				CreatedID:   r.CreatedID,
+				CreatedName: r.CreatedName,
+				Modified:    (r.ModifiedTS != nil),
+				UpVotes:     r.UpVotes,
+				DownVotes:   r.DownVotes,
+				Pinned:      nil, // by convention not present for replies
+				Comment:     r.Comment,
+			})
 		}
 
 		commentList = append(commentList, comment)
@@ -295,6 +403,60 @@ func (m CommentModel) ListComments(profileId string, userID string) ([]CommentLi
 	return commentList, nil
 }
 
+// GetCoCommenters returns the users who commented on the same profiles as the given user (used for suggestions)
+// only comments are taken into account (no replies), the user's most recent ones
+func (m CommentModel) GetCoCommenters(userOID primitive.ObjectID, profileType string) (ProfileParticipants, error) {
+
+	filter := bson.D{
+		{Key: "createdID", Value: userOID},
+		{Key: "profileType", Value: profileType},
+		{Key: "deletedTS", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "profileId", Value: 1}}).
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(SuggestionProfileLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var comments []Comment
+	err = cursor.All(ctx, &comments)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if len(comments) == 0 {
+		return ProfileParticipants{}, nil
+	}
+
+	profileOIDs := make([]primitive.ObjectID, len(comments))
+	for i, comment := range comments {
+		profileOIDs[i] = comment.ProfileID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "profileId", Value: bson.D{{Key: "$in", Value: profileOIDs}}},
+			{Key: "createdID", Value: bson.D{{Key: "$ne", Value: userOID}}},
+			{Key: "statusCD", Value: lookups.CommentStatusVisible},
+			{Key: "deletedTS", Value: bson.D{{Key: "$exists", Value: false}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$createdID"},
+			{Key: "profileIDs", Value: bson.D{{Key: "$addToSet", Value: "$profileId"}}},
+		}}},
+	}
+
+	return aggregateParticipants(ctx, m.Collection, pipeline)
+}
+
 // SetRating is called by the voting model
 func (m CommentModel) SetRating(social *Social) error {
 
@@ -358,3 +520,297 @@ func (m CommentModel) SetRating(social *Social) error {
 
 	return nil
 }
+
+// DeleteComments removes all comments (including their replies) of a profile
+// the IDs of the removed comments and replies are returned, so their votes can be deleted as well
+func (m CommentModel) DeleteComments(profileOID primitive.ObjectID) ([]primitive.ObjectID, error) {
+
+	filter := bson.D{{Key: "profileId", Value: profileOID}}
+
+	fields := bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "replies._id", Value: 1},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Collection.Find(ctx, filter, options.Find().SetProjection(fields))
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var comments []Comment
+	err = cursor.All(ctx, &comments)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var ids []primitive.ObjectID
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+		for _, r := range c.Replies {
+			ids = append(ids, r.ID)
+		}
+	}
+
+	_, err = m.Collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return ids, nil
+}
+
+// DeleteComment moves a comment or reply to the trash bin (creator or admin)
+func (m CommentModel) DeleteComment(commentID string, userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	credentials := m.GetCredentials(userID, false)
+	if credentials.RoleCode == lookups.UserRoleGuest {
+		return apperror.ErrGuest
+	}
+	isAdmin := credentials.RoleCode == lookups.UserRoleAdmin
+
+	now := time.Now()
+	userName := credentials.LoginName
+
+	// top-level comment first
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "deletedTS", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	if !isAdmin {
+		filter = append(filter, bson.E{Key: "createdID", Value: credentials.UserID})
+	}
+
+	fields := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "deletedTS", Value: now}}},
+		{Key: "$set", Value: bson.D{{Key: "deletedID", Value: credentials.UserID}}},
+		{Key: "$set", Value: bson.D{{Key: "deletedName", Value: userName}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// embedded reply (same approach as in SetRating)
+	match := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "deletedTS", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	if !isAdmin {
+		match = append(match, bson.E{Key: "createdID", Value: credentials.UserID})
+	}
+
+	filter = bson.D{
+		{Key: "replies", Value: bson.D{{Key: "$elemMatch", Value: match}}},
+	}
+
+	fields = bson.D{
+		{Key: "$set", Value: bson.D{{Key: "replies.$.deletedTS", Value: now}}},
+		{Key: "$set", Value: bson.D{{Key: "replies.$.deletedID", Value: credentials.UserID}}},
+		{Key: "$set", Value: bson.D{{Key: "replies.$.deletedName", Value: userName}}},
+	}
+
+	result, err = m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		// not found, already deleted or not the user's comment
+		return apperror.ErrNoData
+	}
+
+	return nil
+}
+
+// RestoreComment takes a comment or reply out of the trash bin (creator or admin, within the retention period)
+func (m CommentModel) RestoreComment(commentID string, userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	credentials := m.GetCredentials(userID, false)
+	isAdmin := credentials.RoleCode == lookups.UserRoleAdmin
+
+	retention := bson.D{{Key: "$gt", Value: time.Now().Add(-TrashRetention())}}
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "deletedTS", Value: retention},
+	}
+	if !isAdmin {
+		filter = append(filter, bson.E{Key: "createdID", Value: credentials.UserID})
+	}
+
+	fields := bson.D{
+		{Key: "$unset", Value: bson.D{
+			{Key: "deletedTS", Value: ""},
+			{Key: "deletedID", Value: ""},
+			{Key: "deletedName", Value: ""},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// embedded reply
+	match := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "deletedTS", Value: retention},
+	}
+	if !isAdmin {
+		match = append(match, bson.E{Key: "createdID", Value: credentials.UserID})
+	}
+
+	filter = bson.D{
+		{Key: "replies", Value: bson.D{{Key: "$elemMatch", Value: match}}},
+	}
+
+	fields = bson.D{
+		{Key: "$unset", Value: bson.D{
+			{Key: "replies.$.deletedTS", Value: ""},
+			{Key: "replies.$.deletedID", Value: ""},
+			{Key: "replies.$.deletedName", Value: ""},
+		}},
+	}
+
+	result, err = m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		// not in trash bin, expired or not the user's comment
+		return apperror.ErrNoData
+	}
+
+	return nil
+}
+
+// PurgeComments finally removes comments and replies which are in the trash bin longer than the retention period
+// usually called by a GO-routine that runs in a ticker
+func (m CommentModel) PurgeComments() {
+
+	cutoff := time.Now().Add(-TrashRetention())
+	expired := bson.D{{Key: "$lte", Value: cutoff}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// comments (including all of their replies)
+	filter := bson.D{{Key: "deletedTS", Value: expired}}
+
+	fields := bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "replies._id", Value: 1},
+	}
+
+	var ids []primitive.ObjectID
+
+	cursor, err := m.Collection.Find(ctx, filter, options.Find().SetProjection(fields))
+	if err != nil {
+		// ToDO: Log
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	var comments []Comment
+	err = cursor.All(ctx, &comments)
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+		for _, r := range c.Replies {
+			ids = append(ids, r.ID)
+		}
+	}
+
+	_, err = m.Collection.DeleteMany(ctx, filter)
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	// replies of remaining comments
+	match := bson.D{{Key: "deletedTS", Value: expired}}
+	filter = bson.D{
+		{Key: "replies", Value: bson.D{{Key: "$elemMatch", Value: match}}},
+	}
+
+	fields = bson.D{
+		{Key: "replies._id", Value: 1},
+		{Key: "replies.deletedTS", Value: 1},
+	}
+
+	cursor, err = m.Collection.Find(ctx, filter, options.Find().SetProjection(fields))
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	comments = nil
+	err = cursor.All(ctx, &comments)
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	for _, c := range comments {
+		for _, r := range c.Replies {
+			if r.DeletedTS != nil && !r.DeletedTS.After(cutoff) {
+				ids = append(ids, r.ID)
+			}
+		}
+	}
+
+	update := bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "replies", Value: match}}},
+	}
+
+	_, err = m.Collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	// votes to the removed comments and replies
+	err = m.DeleteVotes(ids)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Printf("%v: %v comment(s) purged.\n", time.Now().Format(time.RFC3339), len(ids))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"forza-garage/apperror"
+	"forza-garage/authorization"
 	"forza-garage/database"
 	"forza-garage/helpers"
 	"forza-garage/lookups"
@@ -41,6 +42,13 @@ type Course struct {
 	Tags           []string           `json:"tags" bson:"tags,omitempty"`
 }
 
+// CourseFork holds the values of a derived course which differ from the original
+type CourseFork struct {
+	Name           string `json:"name"` // optional, taken from the original if empty
+	ForzaSharing   int32  `json:"forzaSharing"`
+	VisibilityCode int32  `json:"visibilityCode"`
+}
+
 // CourseRef is used as a reference
 type CourseRef struct {
 	ID   primitive.ObjectID `json:"id" bson:"_id"`
@@ -64,6 +72,7 @@ type CourseListItem struct {
 	StyleCode    int32              `json:"styleCode"`
 	StyleText    string             `json:"styleText"`
 	CarClasses   []Lookup           `json:"carClasses" bson:"carClasses"`
+	Visits       int64              `json:"visits"`
 }
 
 const (
@@ -81,9 +90,148 @@ type CourseSearchParams struct {
 	GameCode    int32
 	SeriesCodes []int32
 	SearchTerm  string
+	// optional filters
+	CarClasses   []int32            // any of
+	StyleCodes   []int32            // circuit/sprint
+	Tags         []string           // all of
+	CreatorID    primitive.ObjectID // NilObjectID if not set
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time // exclusive
+	ModifiedFrom *time.Time
+	ModifiedTo   *time.Time // exclusive
+	SortOrder    string     // CourseSort-constants
+	Cursor       string     // "next"-token of the previous page (empty for the first page)
+	Limit        int64      // page size (default if 0)
 	//Credentials *Credentials
 }
 
+// CourseSearchResult is one page of a course search
+type CourseSearchResult struct {
+	Courses []CourseListItem `json:"courses"`
+	Next    string           `json:"next,omitempty"` // not present on the last page
+	Total   int64            `json:"total"`          // all pages
+}
+
+// page sizes of course searches
+const (
+	CourseSearchDefaultLimit int64 = 20
+	CourseSearchMaxLimit     int64 = 100
+)
+
+// sort orders of course searches
+const (
+	CourseSortTopRated    = "top" // default
+	CourseSortNewest      = "newest"
+	CourseSortMostVisited = "visits"
+)
+
+// courseSortOrder returns the effective sort order (unknown values fall back to the default)
+func courseSortOrder(order string) string {
+	switch order {
+	case CourseSortNewest, CourseSortMostVisited:
+		return order
+	default:
+		return CourseSortTopRated
+	}
+}
+
+// courseSort returns the sort key of a sort order
+// _id makes every sort key unique, which is required by the cursor
+func courseSort(order string) bson.D {
+	switch courseSortOrder(order) {
+	case CourseSortNewest:
+		// the OID contains the creation timestamp
+		return bson.D{
+			{Key: "_id", Value: -1},
+		}
+	case CourseSortMostVisited:
+		return bson.D{
+			{Key: "metaInfo.visits", Value: -1},
+			{Key: "_id", Value: -1},
+		}
+	default:
+		return bson.D{
+			{Key: "metaInfo.ratingSort", Value: -1},
+			{Key: "metaInfo.rating", Value: -1},
+			{Key: "metaInfo.touchedTS", Value: -1},
+			{Key: "_id", Value: -1},
+		}
+	}
+}
+
+// courseCursor holds the sort key of the last course of a page
+// text searches are sorted by the relevance (score) first, followed by the key of the sort order
+type courseCursor struct {
+	SortOrder  string             `json:"s"`
+	Score      float64            `json:"sc,omitempty"` // text searches only
+	RatingSort float32            `json:"rs,omitempty"`
+	Rating     float32            `json:"r,omitempty"`
+	TouchedTS  time.Time          `json:"t,omitempty"`
+	Visits     int64              `json:"v,omitempty"`
+	ID         primitive.ObjectID `json:"id"`
+}
+
+// textFilter selects the courses following the cursor of a text search
+// the score must be added to the documents before ($addFields), it can't be queried by find
+func (c courseCursor) textFilter() bson.D {
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "score", Value: bson.D{{Key: "$lt", Value: c.Score}}}},
+		bson.D{
+			{Key: "score", Value: c.Score},
+			{Key: "$and", Value: bson.A{c.filter()}},
+		},
+	}}}
+}
+
+// scoredCourse receives a course of a text search along with its relevance
+type scoredCourse struct {
+	Course `bson:",inline"`
+	Score  float64 `bson:"score"`
+}
+
+// filter selects the courses following the cursor (sort order is descending on every field)
+func (c courseCursor) filter() bson.D {
+	switch c.SortOrder {
+	case CourseSortNewest:
+		return bson.D{{Key: "_id", Value: bson.D{{Key: "$lt", Value: c.ID}}}}
+	case CourseSortMostVisited:
+		// visits are not stored until replicated from analytics, missing values are sorted last
+		if c.Visits == 0 {
+			return bson.D{
+				{Key: "metaInfo.visits", Value: bson.D{{Key: "$exists", Value: false}}},
+				{Key: "_id", Value: bson.D{{Key: "$lt", Value: c.ID}}},
+			}
+		}
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "metaInfo.visits", Value: bson.D{{Key: "$lt", Value: c.Visits}}}},
+			bson.D{{Key: "metaInfo.visits", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{
+				{Key: "metaInfo.visits", Value: c.Visits},
+				{Key: "_id", Value: bson.D{{Key: "$lt", Value: c.ID}}},
+			},
+		}}}
+	default:
+		return bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "metaInfo.ratingSort", Value: bson.D{{Key: "$lt", Value: c.RatingSort}}}},
+			bson.D{
+				{Key: "metaInfo.ratingSort", Value: c.RatingSort},
+				{Key: "metaInfo.rating", Value: bson.D{{Key: "$lt", Value: c.Rating}}},
+			},
+			bson.D{
+				{Key: "metaInfo.ratingSort", Value: c.RatingSort},
+				{Key: "metaInfo.rating", Value: c.Rating},
+				{Key: "metaInfo.touchedTS", Value: bson.D{{Key: "$lt", Value: c.TouchedTS}}},
+			},
+			bson.D{
+				{Key: "metaInfo.ratingSort", Value: c.RatingSort},
+				{Key: "metaInfo.rating", Value: c.Rating},
+				{Key: "metaInfo.touchedTS", Value: c.TouchedTS},
+				{Key: "_id", Value: bson.D{{Key: "$lt", Value: c.ID}}},
+			},
+		}}}
+	}
+}
+
 /*
 type CredentialsReader interface {
 	GetCredentials(userId string) (*Credentials, error)
@@ -93,17 +241,41 @@ type CredentialsReader interface {
 type CourseModel struct {
 	Client     *mongo.Client
 	Collection *mongo.Collection
+	Revisions  *mongo.Collection // previous versions of courses
 	// Gewisse Informationen kommen vom User-Model, die werden hier referenziert
 	// somit muss das nicht der Controller machen
 	GetUserName func(ID string) (string, error)
 	// ToDo: halt umbennen GetCredentials
-	CredentialsReader func(userId string, loadFriendlist bool) *Credentials
+	CredentialsReader func(userOID primitive.ObjectID, loadFriendlist bool) *authorization.Credentials
 	GetUserVote       func(profileID string, userID string) (int32, error) // injected from vote model
+	// dependent data of other models, removed together with a course
+	DeleteComments      func(profileOID primitive.ObjectID) ([]primitive.ObjectID, error) // injected from comment model
+	DeleteVotes         func(profileOIDs []primitive.ObjectID) error                      // injected from vote model
+	DeleteUploads       func(profileOID primitive.ObjectID) error                         // injected from upload model
+	RemoveObservers     func(profileOID primitive.ObjectID) error                         // injected from user model
+	RemoveNotifications func(profileOID primitive.ObjectID) error                         // injected from notification model
+	// watchlist
+	AddObserver     func(userOID primitive.ObjectID, profileOID primitive.ObjectID, profileName string, profileType string) error // injected from user model
+	NotifyObservers func(profileOID primitive.ObjectID, notificationType string, actorOID primitive.ObjectID, actorName string)   // injected from notification model
+	Notify          func(notification Notification)                                                                               // injected from notification model
 }
 
 // Models do not change original values passed by the controllers, but return new structures
 // arguments (usually) passed by ref (pointers) for performance
 
+// ForzaSharingRange is the valid range of the share codes of a game
+type ForzaSharingRange struct {
+	Min int32
+	Max int32
+}
+
+// ForzaSharingRanges lists the games supporting share codes
+// the games display them as 9 digits (eg. "123 456 789")
+var ForzaSharingRanges = map[int32]ForzaSharingRange{
+	lookups.GameFH4: {Min: 100000000, Max: 999999999},
+	lookups.GameFH5: {Min: 100000000, Max: 999999999},
+}
+
 // Validate checks given values and sets defaults where applicable (immutable)
 func (m CourseModel) Validate(course Course) (*Course, error) {
 
@@ -113,19 +285,38 @@ func (m CourseModel) Validate(course Course) (*Course, error) {
 	// Clean Strings
 	// Validate Code Values (?) -> dann geht es nicxht mit Const/Enum, sondern const-array
 	// ..according to model
-	// Forza Share Code (Range)
 
 	cleaned.Name = strings.TrimSpace(cleaned.Name)
-	if course.Name == "" {
+	if cleaned.Name == "" {
 		return nil, ErrCourseNameMissing
 	}
 
+	err := ValidateForzaSharing(cleaned.GameCode, cleaned.ForzaSharing)
+	if err != nil {
+		return nil, err
+	}
+
 	return &cleaned, nil
 }
 
-// ForzaSharingExists checks if a "Sharing Code" in the game already exists (which is their PK)
+// ValidateForzaSharing checks if a share code is possible in the given game
+func ValidateForzaSharing(gameCode int32, sharingCode int32) error {
+
+	if sharingCode == 0 {
+		return ErrForzaSharingCodeMissing
+	}
+
+	r, ok := ForzaSharingRanges[gameCode]
+	if !ok || sharingCode < r.Min || sharingCode > r.Max {
+		return ErrForzaSharingCodeInvalid
+	}
+
+	return nil
+}
+
+// ForzaSharingExists checks if a "Sharing Code" already exists in the game (which is their PK)
 // this is used for in-line validation while typing in the client's form
-func (m CourseModel) ForzaSharingExists(sharingCode int32) (bool, error) {
+func (m CourseModel) ForzaSharingExists(gameCode int32, sharingCode int32) (bool, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
@@ -138,7 +329,12 @@ func (m CourseModel) ForzaSharingExists(sharingCode int32) (bool, error) {
 		ID primitive.ObjectID `bson:"_id"`
 	}{}
 
-	err := m.Collection.FindOne(ctx, bson.M{"forzaSharing": sharingCode}, options.FindOne().SetProjection(fields)).Decode(&data)
+	filter := bson.D{
+		{Key: "gameCD", Value: gameCode},
+		{Key: "forzaSharing", Value: sharingCode},
+	}
+
+	err := m.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(fields)).Decode(&data)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return false, nil
@@ -150,9 +346,89 @@ func (m CourseModel) ForzaSharingExists(sharingCode int32) (bool, error) {
 	return true, nil
 }
 
+// CheckProfileAccess checks if a user may see a profile of the racing collection (course or championship)
+// eg. before live updates of the profile are sent to the user
+func (m CourseModel) CheckProfileAccess(profileID string, userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(profileID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	fields := bson.D{
+		{Key: "visibilityCD", Value: 1},
+		{Key: "metaInfo.createdID", Value: 1},
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // trash bin
+	}
+
+	var data Course
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err = m.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(fields)).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData
+		}
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false).LookupFriendship()
+
+	// no wrapping needed, since function returns app errors
+	return authorization.GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
+}
+
+// GetCourseByShareCode returns the course of a game's share code
+// (the code is unique per game, deleted courses are not found)
+func (m CourseModel) GetCourseByShareCode(gameCode int32, sharingCode int32, userID string) (*Course, error) {
+
+	fields := bson.D{
+		{Key: "_id", Value: 1}}
+
+	data := struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}{}
+
+	filter := bson.D{
+		{Key: "gameCD", Value: gameCode},
+		{Key: "forzaSharing", Value: sharingCode},
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$exists", Value: true}}},        // courses only
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // trash bin
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(fields)).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperror.ErrNoData
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// permissions and lookups are handled as for any other course
+	return m.GetCourse(data.ID.Hex(), userID)
+}
+
 // CreateCourse adds a new route - validated by controller
 func (m CourseModel) CreateCourse(course *Course, userID string) (string, error) {
 
+	// users create custom routes only (standard routes are imported)
+	course.TypeCode = lookups.CourseTypeCustom
+
+	return m.insertCourse(course, userID)
+}
+
+// insertCourse sets the system fields and saves a new course of any type
+func (m CourseModel) insertCourse(course *Course, userID string) (string, error) {
+
 	// set "system-fields"
 	course.ID = primitive.NewObjectID()
 	// course.MetaInfo.CreatedTS set by ID via OID
@@ -166,7 +442,9 @@ func (m CourseModel) CreateCourse(course *Course, userID string) (string, error)
 	course.MetaInfo.TouchedTS = time.Now()
 	course.MetaInfo.Rating = 0
 	course.MetaInfo.RecVer = 1
-	course.TypeCode = lookups.CourseTypeCustom
+	course.MetaInfo.DeletedTS = nil
+	course.MetaInfo.DeletedID = nil
+	course.MetaInfo.DeletedName = ""
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
@@ -176,9 +454,9 @@ func (m CourseModel) CreateCourse(course *Course, userID string) (string, error)
 		// leider können DB-Error Codes nicht direkt aus dem Fehler ausgelesen werden
 		// https://stackoverflow.com/questions/56916969/with-mongodb-go-driver-how-do-i-get-the-inner-exceptions
 
-		if (err.(mongo.WriteException).WriteErrors[0].Code) == 11000 {
+		if we, ok := err.(mongo.WriteException); ok && len(we.WriteErrors) > 0 && we.WriteErrors[0].Code == 11000 {
 			// Error 11000 = DUP
-			// since there is only one unique index in the collection, it's a duplicate forza share code
+			// since there is only one unique index in the collection, it's a duplicate forza share code (per game)
 			return "", ErrForzaSharingCodeTaken
 		}
 		// any other error
@@ -188,19 +466,64 @@ func (m CourseModel) CreateCourse(course *Course, userID string) (string, error)
 	return res.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
-// SearchCourses lists or searches course (ohne Comments, aber mit Files/Tags)
-// ACHTUNG: Die Liste wird sortiert und limitiert, daher können einzelne Dokumente herausfallen ;-)
-func (m CourseModel) SearchCourses(searchSpecs *CourseSearchParams, userID string) ([]CourseListItem, error) {
+// ForkCourse creates a custom course of the user which is derived from a visible course
+// the original is referenced by "route" to preserve attribution
+func (m CourseModel) ForkCourse(courseID string, fork *CourseFork, userID string) (string, error) {
 
-	// CourseListeItem: Verkleinerte/vereinfachte Struktur für Listen
-	// MongoDB muss eine passende Struktur erhalten um die Daten aufzunehmen (z. B. mit nested Arrays)
-	// das API kann die Daten dann in die Listenstruktur kopieren
-	// daher wird zum Aufnehmen der Daten aus der DB immer mit der Original-Struktur gearbeitet
-	// Speicherbedarf bleibt halt gleich, dafür nimmt die Netzlast ab
+	original, err := m.readCurrent(courseID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	// a fork is a new design in the game, thus it has its own share code
+	err = ValidateForzaSharing(original.GameCode, fork.ForzaSharing)
+	if err != nil {
+		return "", err
+	}
+
+	course := Course{
+		VisibilityCode: fork.VisibilityCode,
+		GameCode:       original.GameCode,
+		StyleCode:      original.StyleCode,
+		ForzaSharing:   fork.ForzaSharing,
+		Name:           strings.TrimSpace(fork.Name),
+		SeriesCode:     original.SeriesCode,
+		CarClasses:     original.CarClasses,
+		Description:    original.Description,
+		Route:          &CourseRef{ID: original.ID, Name: original.Name},
+		Tags:           original.Tags,
+	}
+	if course.Name == "" {
+		course.Name = original.Name
+	}
+
+	// system fields & type are set by create
+	return m.CreateCourse(&course, userID)
+}
+
+// ObserveCourse adds a visible course to the user's watchlist
+// observers are notified when the course is updated, commented or receives an upload
+func (m CourseModel) ObserveCourse(courseID string, userID string) error {
+
+	course, err := m.readCurrent(courseID, userID)
+	if err != nil {
+		return err
+	}
+
+	return m.AddObserver(helpers.ObjectID(userID), course.ID, course.Name, "course")
+}
+
+// ListForks returns the visible courses which are derived from a given course
+func (m CourseModel) ListForks(courseID string, userID string) ([]CourseListItem, error) {
+
+	id, err := primitive.ObjectIDFromHex(courseID)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
 
 	fields := bson.D{
-		{Key: "_id", Value: 1},      // _id kommt immer, ausser es wird explizit ausgeschlossen (0)
-		{Key: "metaInfo", Value: 1}, // {Key: "metaInfo.rating", Value: 1}, -- so könnte die nested struct eingeschränkt werden
+		{Key: "_id", Value: 1},
+		{Key: "metaInfo", Value: 1},
 		{Key: "gameCD", Value: 1},
 		{Key: "name", Value: 1},
 		{Key: "forzaSharing", Value: 1},
@@ -209,161 +532,20 @@ func (m CourseModel) SearchCourses(searchSpecs *CourseSearchParams, userID strin
 		{Key: "carClasses", Value: 1},
 	}
 
-	sort := bson.D{
-		{Key: "metaInfo.ratingSort", Value: -1},
-		{Key: "metaInfo.rating", Value: -1},
-		{Key: "metaInfo.touchedTS", Value: -1},
-	}
-
-	opts := options.Find().SetProjection(fields).SetLimit(20).SetSort(sort)
-
-	// https://docs.mongodb.com/manual/tutorial/query-documents/
-	// https://docs.mongodb.com/manual/reference/operator/query/#query-selectors
-	// https://stackoverflow.com/questions/3305561/how-to-query-mongodb-with-like
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), true)
 
-	// perhaps, the searchTerm is a forza share code
-	i, _ := strconv.Atoi(searchSpecs.SearchTerm)
-
-	// construct a document containing the search parameters
-	// filter := bson.D{}
-	var filter bson.D
+	filter := authorization.NewFilter().
+		Field("route._id", id).
+		Field("metaInfo.deletedTS", bson.D{{Key: "$exists", Value: false}}). // trash bin
+		Visible(credentials, "metaInfo.createdID").
+		Build()
 
-	// build IN-List of course types
-	var courseTypes []int32
-	switch searchSpecs.SearchMode {
-	case CourseSearchModeAll:
-		courseTypes = append(courseTypes, lookups.CourseTypeStandard)
-		courseTypes = append(courseTypes, lookups.CourseTypeCustom)
-	case CourseSearchModeStandard:
-		courseTypes = append(courseTypes, lookups.CourseTypeStandard)
-	case CourseSearchModeCustom:
-		courseTypes = append(courseTypes, lookups.CourseTypeCustom)
+	// newest first
+	sort := bson.D{
+		{Key: "_id", Value: -1},
 	}
 
-	credentials := m.CredentialsReader(userID, true)
-
-	if credentials.RoleCode == lookups.UserRoleGuest {
-		// anonymous visitors will only receive PUBLIC routes
-		if searchSpecs.SearchTerm == "" {
-			filter = bson.D{
-				// every next field is AND
-				{Key: "gameCD", Value: searchSpecs.GameCode}, // $eq kann wegelassen werden
-				{Key: "courseTypeCD", Value: bson.D{ // selects courses rather than championships, just like $exists
-					{Key: "$in", Value: courseTypes},
-				}},
-				{Key: "seriesCD", Value: bson.D{
-					{Key: "$in", Value: searchSpecs.SeriesCodes},
-				}},
-				{Key: "visibilityCD", Value: lookups.VisibilityAll},
-			}
-			fmt.Println(filter)
-		} else {
-			filter = bson.D{
-				// every next field is AND
-				{Key: "gameCD", Value: searchSpecs.GameCode}, // $eq kann wegelassen werden
-				{Key: "courseTypeCD", Value: bson.D{ // selects courses rather than championships, just like $exists
-					{Key: "$in", Value: courseTypes},
-				}},
-				{Key: "seriesCD", Value: bson.D{
-					{Key: "$in", Value: searchSpecs.SeriesCodes},
-				}},
-				{Key: "visibilityCD", Value: lookups.VisibilityAll},
-				{Key: "$or", Value: bson.A{ // AND OR (...
-					bson.D{{Key: "name", Value: primitive.Regex{Pattern: ".*" + searchSpecs.SearchTerm + ".*", Options: "/i"}}}, // LIKE %searchTerm% (case-insensitive)
-					bson.D{{Key: "forzaSharing", Value: i}}, // 0 if searchTerm was alpha-numeric
-				}},
-			}
-		}
-	} else {
-		// if a user is logged-in, check their privilidges (must be Admin or Member)
-		//fmt.Printf("%s is logged in with role %v", credentials.LoginName, credentials.RoleCode)
-		if credentials.RoleCode == lookups.UserRoleAdmin {
-			// no visibility check needed for admins
-			if searchSpecs.SearchTerm == "" {
-				filter = bson.D{
-					// every next field is AND
-					{Key: "gameCD", Value: searchSpecs.GameCode}, // $eq kann wegelassen werden
-					{Key: "courseTypeCD", Value: bson.D{ // selects courses rather than championships, just like $exists
-						{Key: "$in", Value: courseTypes},
-					}},
-					{Key: "seriesCD", Value: bson.D{
-						{Key: "$in", Value: searchSpecs.SeriesCodes},
-					}},
-					// visibility check removed
-				}
-			} else {
-				// apply search Term
-				filter = bson.D{
-					// every next field is AND
-					{Key: "gameCD", Value: searchSpecs.GameCode}, // $eq kann wegelassen werden
-					{Key: "courseTypeCD", Value: bson.D{ // selects courses rather than championships, just like $exists
-						{Key: "$in", Value: courseTypes},
-					}},
-					{Key: "seriesCD", Value: bson.D{
-						{Key: "$in", Value: searchSpecs.SeriesCodes},
-					}},
-					// visibility check removed
-					{Key: "$or", Value: bson.A{ // AND OR (...
-						bson.D{{Key: "name", Value: primitive.Regex{Pattern: ".*" + searchSpecs.SearchTerm + ".*", Options: "/i"}}}, // LIKE %searchTerm% (case-insensitive)
-						bson.D{{Key: "forzaSharing", Value: i}}, // 0 if searchTerm was alpha-numeric
-					}},
-				}
-			}
-		} else {
-			// check visibility
-			friendIDs := make([]primitive.ObjectID, len(credentials.Friends))
-			for i, friend := range credentials.Friends {
-				friendIDs[i] = friend.ReferenceID
-			}
-
-			if searchSpecs.SearchTerm == "" {
-				filter = bson.D{
-					// every next field is AND
-					{Key: "gameCD", Value: searchSpecs.GameCode}, // $eq kann wegelassen werden
-					{Key: "courseTypeCD", Value: bson.D{ // selects courses rather than championships, just like $exists
-						{Key: "$in", Value: courseTypes},
-					}},
-					{Key: "seriesCD", Value: bson.D{
-						{Key: "$in", Value: searchSpecs.SeriesCodes},
-					}},
-					// visibility check
-					{Key: "$or", Value: bson.A{
-						bson.D{{Key: "visibilityCD", Value: 0}},
-						bson.D{{Key: "metaInfo.createdID", Value: credentials.UserID}},
-						bson.D{{Key: "$and", Value: bson.A{
-							bson.D{{Key: "visibilityCD", Value: 1}},
-							bson.D{{Key: "metaInfo.createdID", Value: bson.D{{Key: "$in", Value: friendIDs}}}}, // nested doc for $in
-						}}}, // nested $and-array im $or
-					}}, // $or-array
-				}
-			} else {
-				filter = bson.D{
-					// every next field is AND
-					{Key: "gameCD", Value: searchSpecs.GameCode}, // $eq kann wegelassen werden
-					{Key: "courseTypeCD", Value: bson.D{ // selects courses rather than championships, just like $exists
-						{Key: "$in", Value: courseTypes},
-					}},
-					{Key: "seriesCD", Value: bson.D{
-						{Key: "$in", Value: searchSpecs.SeriesCodes},
-					}},
-					// visibility check
-					{Key: "$or", Value: bson.A{
-						bson.D{{Key: "visibilityCD", Value: 0}},
-						bson.D{{Key: "metaInfo.createdID", Value: credentials.UserID}},
-						bson.D{{Key: "$and", Value: bson.A{
-							bson.D{{Key: "visibilityCD", Value: 1}},
-							bson.D{{Key: "metaInfo.createdID", Value: bson.D{{Key: "$in", Value: friendIDs}}}}, // nested doc for $in
-						}}}, // nested $and-array im $or
-					}}, // $or-array
-					// apply search term
-					{Key: "$or", Value: bson.A{ // AND OR (...
-						bson.D{{Key: "name", Value: primitive.Regex{Pattern: ".*" + searchSpecs.SearchTerm + ".*", Options: "/i"}}}, // LIKE %searchTerm% (case-insensitive)
-						bson.D{{Key: "forzaSharing", Value: i}}, // 0 if searchTerm was alpha-numeric
-					}}, // $or-array
-				}
-			}
-		}
-	}
+	opts := options.Find().SetProjection(fields).SetLimit(50).SetSort(sort)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
@@ -373,121 +555,462 @@ func (m CourseModel) SearchCourses(searchSpecs *CourseSearchParams, userID strin
 		return nil, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	// receive results
 	var courses []Course
-
 	err = cursor.All(ctx, &courses)
 	if err != nil {
 		return nil, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	// check for empty result set (no error raised by find)
 	if courses == nil {
 		return nil, apperror.ErrNoData
 	}
 
-	// copy data to reduced list-struct
-	var courseList []CourseListItem
-	var course CourseListItem
-
-	for _, c := range courses {
-		course.ID = c.ID
-		course.CreatedTS = primitive.ObjectID.Timestamp(c.ID)
-		course.CreatedID = c.MetaInfo.CreatedID
-		course.CreatedName = c.MetaInfo.CreatedName
-		course.Rating = c.MetaInfo.Rating
-		course.GameCode = c.GameCode
-		course.GameText = database.GetLookupText(lookups.LookupType(lookups.LTgame), c.GameCode)
-		course.Name = c.Name
-		course.ForzaSharing = c.ForzaSharing
-		course.SeriesCode = c.SeriesCode
-		course.SeriesText = database.GetLookupText(lookups.LookupType(lookups.LTseries), c.SeriesCode)
-		course.StyleCode = c.StyleCode
-		course.StyleText = database.GetLookupText(lookups.LookupType(lookups.LTcourseStyle), c.StyleCode)
-		if len(c.CarClasses) > 0 {
-			course.CarClasses = make([]Lookup, len(c.CarClasses))
-			for i, v := range c.CarClasses {
-				course.CarClasses[i].Value = v.Value
-				course.CarClasses[i].Text = database.GetLookupText(lookups.LookupType(lookups.LTcarClass), v.Value)
-			}
-		}
-
-		courseList = append(courseList, course)
-	}
-
-	return courseList, nil
+	return m.toListItems(courses), nil
 }
 
-// GetCourse returns one
-func (m CourseModel) GetCourse(courseID string, userID string) (*Course, error) {
-	//func (m CourseModel) GetCourse(courseID string, credentials *Credentials) (*Course, error) {
+// GetPublicCourses returns the most recent public courses of a user and their total number (shown in the profile)
+func (m CourseModel) GetPublicCourses(creatorOID primitive.ObjectID, limit int64) ([]CourseListItem, int64, error) {
 
-	id, err := primitive.ObjectIDFromHex(courseID)
-	if err != nil {
-		return nil, apperror.ErrNoData
+	fields := bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "metaInfo", Value: 1},
+		{Key: "gameCD", Value: 1},
+		{Key: "name", Value: 1},
+		{Key: "forzaSharing", Value: 1},
+		{Key: "seriesCD", Value: 1},
+		{Key: "styleCD", Value: 1},
+		{Key: "carClasses", Value: 1},
 	}
 
-	data := Course{}
+	// public items only, no matter who's looking
+	filter := bson.D{
+		{Key: "metaInfo.createdID", Value: creatorOID},
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$in", Value: bson.A{lookups.CourseTypeStandard, lookups.CourseTypeCustom}}}},
+		{Key: "visibilityCD", Value: lookups.VisibilityAll},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
 
-	// später vielleicht project() wenn's zu viele felder werden (excl. nested oder sowas)
-	err = m.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&data)
+	total, err := m.Collection.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, apperror.ErrNoData
+		return nil, 0, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if total == 0 {
+		return nil, 0, nil
 	}
-	// extract creation timestamp from OID
-	data.MetaInfo.CreatedTS = primitive.ObjectID(id).Timestamp()
 
-	credentials := m.CredentialsReader(userID, true)
+	// newest first
+	opts := options.Find().SetProjection(fields).SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
 
-	err = GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
+	cursor, err := m.Collection.Find(ctx, filter, opts)
 	if err != nil {
-		// no wrapping needed, since function returns app errors
-		return nil, err
+		return nil, 0, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	// get user's vote if present
-	if userID != "" {
-		// fehler kann hier ignoriert werden (default = 0 = note voted)
-		uv, _ := m.GetUserVote(courseID, userID)
-		data.MetaInfo.UserVote = uv
+	var courses []Course
+	err = cursor.All(ctx, &courses)
+	if err != nil {
+		return nil, 0, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	m.addLookups(&data)
-
-	return &data, nil
+	return m.toListItems(courses), total, nil
 }
 
-// UpdateCourse modifies a given course
-func (m CourseModel) UpdateCourse(course *Course, userID string) error {
+// SearchCourses lists or searches course (ohne Comments, aber mit Files/Tags)
+// the list is paged by a cursor over the sort key, so no document falls out of the list
+func (m CourseModel) SearchCourses(searchSpecs *CourseSearchParams, userID string) (*CourseSearchResult, error) {
+
+	// CourseListeItem: Verkleinerte/vereinfachte Struktur für Listen
+	// MongoDB muss eine passende Struktur erhalten um die Daten aufzunehmen (z. B. mit nested Arrays)
+	// das API kann die Daten dann in die Listenstruktur kopieren
+	// daher wird zum Aufnehmen der Daten aus der DB immer mit der Original-Struktur gearbeitet
+	// Speicherbedarf bleibt halt gleich, dafür nimmt die Netzlast ab
 
-	// read "metadata" to check permissions and perform optimistic locking
-	// könnte eigentlich ausgelagert werden
 	fields := bson.D{
-		{Key: "_id", Value: 0},
-		{Key: "metaInfo.createdID", Value: 1},
-		{Key: "metaInfo.recVer", Value: 1},
-		{Key: "visibilityCD", Value: 1},
+		{Key: "_id", Value: 1},      // _id kommt immer, ausser es wird explizit ausgeschlossen (0)
+		{Key: "metaInfo", Value: 1}, // {Key: "metaInfo.rating", Value: 1}, -- so könnte die nested struct eingeschränkt werden
+		{Key: "gameCD", Value: 1},
+		{Key: "name", Value: 1},
+		{Key: "forzaSharing", Value: 1},
+		{Key: "seriesCD", Value: 1},
+		{Key: "styleCD", Value: 1},
+		{Key: "carClasses", Value: 1},
 	}
 
-	filter := bson.D{{Key: "_id", Value: course.ID}}
+	limit := searchSpecs.Limit
+	if limit <= 0 || limit > CourseSearchMaxLimit {
+		limit = CourseSearchDefaultLimit
+	}
 
-	data := struct {
-		CreatedID      primitive.ObjectID `bson:"metaInfo.createdID"`
-		MetaInfo       Header             `bson:"metaInfo"` // declare & reserve entire nested object (seems required by driver)
-		VisibilityCode int32              `bson:"visibilityCD"`
-	}{}
+	// a share code is unique within the game, hence its result never has a next page
+	// and a cursor always continues a text search
+	byShareCode := searchSpecs.Cursor == "" && isShareCodeTerm(searchSpecs.SearchTerm)
+	filter, sort, textSearch := m.searchQuery(searchSpecs, userID, byShareCode)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
 
-	// alternative schreibweisen:
+	// the total refers to all pages, hence it's counted before the cursor is applied
+	total, err := m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// no share code found, numeric terms may still be part of a name (eg. "2077")
+	if byShareCode && total == 0 {
+		filter, sort, textSearch = m.searchQuery(searchSpecs, userID, false)
+		total, err = m.Collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, helpers.WrapError(err, helpers.FuncName())
+		}
+	}
+
+	// continue after the last item of the previous page
+	var last *courseCursor
+	if searchSpecs.Cursor != "" {
+		last = &courseCursor{}
+		err = helpers.DecodeCursor(searchSpecs.Cursor, last)
+		// the cursor is only valid for the sort order it was created for
+		if err != nil || last.SortOrder != courseSortOrder(searchSpecs.SortOrder) {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	// one more than requested tells if there's a next page
+	var courses []scoredCourse
+	if textSearch {
+		courses, err = m.findScoredCourses(ctx, filter, sort, fields, limit+1, last)
+	} else {
+		if last != nil {
+			filter = bson.D{{Key: "$and", Value: bson.A{filter, last.filter()}}}
+		}
+		opts := options.Find().SetProjection(fields).SetLimit(limit + 1).SetSort(sort)
+
+		var cursor *mongo.Cursor
+		cursor, err = m.Collection.Find(ctx, filter, opts)
+		if err == nil {
+			err = cursor.All(ctx, &courses)
+		}
+	}
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// check for empty result set (no error raised by find)
+	if courses == nil {
+		return nil, apperror.ErrNoData
+	}
+
+	result := CourseSearchResult{Total: total}
+
+	if int64(len(courses)) > limit {
+		courses = courses[:limit]
+		c := courses[len(courses)-1]
+		next := courseCursor{
+			SortOrder:  courseSortOrder(searchSpecs.SortOrder),
+			Score:      c.Score,
+			RatingSort: c.MetaInfo.RatingSort,
+			Rating:     c.MetaInfo.Rating,
+			TouchedTS:  c.MetaInfo.TouchedTS,
+			Visits:     c.MetaInfo.Visits,
+			ID:         c.ID,
+		}
+		result.Next, err = helpers.EncodeCursor(next)
+		if err != nil {
+			return nil, helpers.WrapError(err, helpers.FuncName())
+		}
+	}
+
+	list := make([]Course, len(courses))
+	for i, c := range courses {
+		list[i] = c.Course
+	}
+
+	result.Courses = m.toListItems(list)
+
+	return &result, nil
+}
+
+// findScoredCourses reads a page of a text search, sorted by relevance
+// the score is added as a field, so the cursor can continue after it just like with the other sort keys
+func (m CourseModel) findScoredCourses(ctx context.Context, filter bson.D, sort bson.D, fields bson.D, limit int64, last *courseCursor) ([]scoredCourse, error) {
+
+	// $text must be part of the first stage
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}}},
+	}
+	if last != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: last.textFilter()}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$limit", Value: limit}},
+		bson.D{{Key: "$project", Value: append(fields, bson.E{Key: "score", Value: 1})}},
+	)
+
+	cursor, err := m.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var courses []scoredCourse
+	err = cursor.All(ctx, &courses)
+	if err != nil {
+		return nil, err
+	}
+
+	return courses, nil
+}
+
+// isShareCodeTerm tells if a search term may be a forza share code (numeric)
+func isShareCodeTerm(searchTerm string) bool {
+	_, err := strconv.Atoi(strings.TrimSpace(searchTerm))
+	return err == nil
+}
+
+// searchQuery builds the filter and sort key of a course search (shared by the list and the export)
+// byShareCode looks up a numeric search term as forza share code, otherwise the term is looked up in the text index
+// the sort key of text searches starts with the relevance ("score")
+func (m CourseModel) searchQuery(searchSpecs *CourseSearchParams, userID string, byShareCode bool) (bson.D, bson.D, bool) {
+
+	sort := courseSort(searchSpecs.SortOrder)
+
+	// https://docs.mongodb.com/manual/tutorial/query-documents/
+	// https://docs.mongodb.com/manual/reference/operator/query/#query-selectors
+	// https://docs.mongodb.com/manual/text-search/
+
+	// the share code lookup is tried first by the callers, the text index covers
+	// name, description & tags (see EnsureIndexes)
+	searchTerm := strings.TrimSpace(searchSpecs.SearchTerm)
+	shareCode, err := strconv.Atoi(searchTerm)
+	byShareCode = byShareCode && err == nil
+	textSearch := searchTerm != "" && !byShareCode
+
+	// build IN-List of course types
+	var courseTypes []int32
+	switch searchSpecs.SearchMode {
+	case CourseSearchModeAll:
+		courseTypes = append(courseTypes, lookups.CourseTypeStandard)
+		courseTypes = append(courseTypes, lookups.CourseTypeCustom)
+	case CourseSearchModeStandard:
+		courseTypes = append(courseTypes, lookups.CourseTypeStandard)
+	case CourseSearchModeCustom:
+		courseTypes = append(courseTypes, lookups.CourseTypeCustom)
+	}
+
+	// construct a document containing the search parameters
+	// every next field is AND
+	query := authorization.NewFilter().
+		Field("gameCD", searchSpecs.GameCode).                           // $eq kann wegelassen werden
+		Field("courseTypeCD", bson.D{{Key: "$in", Value: courseTypes}}). // selects courses rather than championships, just like $exists
+		Field("seriesCD", bson.D{{Key: "$in", Value: searchSpecs.SeriesCodes}}).
+		Field("metaInfo.deletedTS", bson.D{{Key: "$exists", Value: false}}) // courses in the trash bin are never listed
+
+	// optional filters
+	if len(searchSpecs.CarClasses) > 0 {
+		// any of the given classes
+		query.Field("carClasses.value", bson.D{{Key: "$in", Value: searchSpecs.CarClasses}})
+	}
+	if len(searchSpecs.StyleCodes) > 0 {
+		query.Field("styleCD", bson.D{{Key: "$in", Value: searchSpecs.StyleCodes}})
+	}
+	if len(searchSpecs.Tags) > 0 {
+		// all of the given tags
+		query.Field("tags", bson.D{{Key: "$all", Value: searchSpecs.Tags}})
+	}
+	if searchSpecs.CreatorID != primitive.NilObjectID {
+		query.Field("metaInfo.createdID", searchSpecs.CreatorID)
+	}
+	// the creation timestamp is part of the OID
+	if searchSpecs.CreatedFrom != nil || searchSpecs.CreatedTo != nil {
+		var created bson.D
+		if searchSpecs.CreatedFrom != nil {
+			created = append(created, bson.E{Key: "$gte", Value: primitive.NewObjectIDFromTimestamp(*searchSpecs.CreatedFrom)})
+		}
+		if searchSpecs.CreatedTo != nil {
+			created = append(created, bson.E{Key: "$lt", Value: primitive.NewObjectIDFromTimestamp(*searchSpecs.CreatedTo)})
+		}
+		query.Field("_id", created)
+	}
+	if searchSpecs.ModifiedFrom != nil || searchSpecs.ModifiedTo != nil {
+		var modified bson.D
+		if searchSpecs.ModifiedFrom != nil {
+			modified = append(modified, bson.E{Key: "$gte", Value: *searchSpecs.ModifiedFrom})
+		}
+		if searchSpecs.ModifiedTo != nil {
+			modified = append(modified, bson.E{Key: "$lt", Value: *searchSpecs.ModifiedTo})
+		}
+		query.Field("metaInfo.modifiedTS", modified)
+	}
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), true)
+	query.Visible(credentials, "metaInfo.createdID")
+
+	// apply search term
+	if textSearch {
+		// relevance goes first, the rating decides among equally relevant courses
+		score := bson.D{{Key: "$meta", Value: "textScore"}}
+		query.Field("$text", bson.D{{Key: "$search", Value: searchTerm}})
+		sort = append(bson.D{{Key: "score", Value: score}}, sort...)
+	} else if byShareCode {
+		query.Field("forzaSharing", shareCode)
+	}
+
+	return query.Build(), sort, textSearch
+}
+
+// EnsureIndexes creates the indexes required by the course queries (called at startup)
+func (m CourseModel) EnsureIndexes() error {
+
+	// only one text index is allowed per collection, it's shared with the championships
+	// weights: a match in the name is more relevant than one in the description
+	text := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "name", Value: "text"},
+			{Key: "description", Value: "text"},
+			{Key: "tags", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("racingText").
+			SetWeights(bson.D{
+				{Key: "name", Value: 10},
+				{Key: "tags", Value: 5},
+				{Key: "description", Value: 1},
+			}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := m.Collection.Indexes().CreateOne(ctx, text)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// share codes are unique per game (championships have none)
+	shareCodes := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "gameCD", Value: 1},
+			{Key: "forzaSharing", Value: 1},
+		},
+		Options: options.Index().
+			SetName("gameForzaSharing").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.D{{Key: "forzaSharing", Value: bson.D{{Key: "$exists", Value: true}}}}),
+	}
+
+	_, err = m.Collection.Indexes().CreateOne(ctx, shareCodes)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// the former index made the share codes unique across all games
+	_, err = m.Collection.Indexes().DropOne(ctx, "forzaSharing_1")
+	if err != nil {
+		if ce, ok := err.(mongo.CommandError); !ok || ce.Code != 27 { // 27 = IndexNotFound
+			return helpers.WrapError(err, helpers.FuncName())
+		}
+	}
+
+	// derived courses (forks)
+	forks := mongo.IndexModel{
+		Keys:    bson.D{{Key: "route._id", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+
+	_, err = m.Collection.Indexes().CreateOne(ctx, forks)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// one revision per version of a course
+	revisions := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "courseID", Value: 1},
+			{Key: "recVer", Value: -1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err = m.Revisions.Indexes().CreateOne(ctx, revisions)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
+// GetCourse returns one
+func (m CourseModel) GetCourse(courseID string, userID string) (*Course, error) {
+	//func (m CourseModel) GetCourse(courseID string, credentials *Credentials) (*Course, error) {
+
+	id, err := primitive.ObjectIDFromHex(courseID)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
+
+	data := Course{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	// später vielleicht project() wenn's zu viele felder werden (excl. nested oder sowas)
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // trash bin
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(&data)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
+	// extract creation timestamp from OID
+	data.MetaInfo.CreatedTS = primitive.ObjectID(id).Timestamp()
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false).LookupFriendship()
+
+	err = authorization.GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
+	if err != nil {
+		// no wrapping needed, since function returns app errors
+		return nil, err
+	}
+
+	// get user's vote if present
+	if userID != "" {
+		// fehler kann hier ignoriert werden (default = 0 = note voted)
+		uv, _ := m.GetUserVote(courseID, userID)
+		data.MetaInfo.UserVote = uv
+	}
+
+	m.addLookups(&data)
+
+	return &data, nil
+}
+
+// UpdateCourse modifies a given course
+func (m CourseModel) UpdateCourse(course *Course, userID string) error {
+
+	// read the current version to check permissions and perform optimistic locking
+	// (the whole document is kept as a revision)
+	filter := bson.D{
+		{Key: "_id", Value: course.ID},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // trash bin
+	}
+
+	var data Course
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	// alternative schreibweisen:
 	//err := m.Collection.FindOne(ctx, bson.D{{Key: "_id", Value: course.ID}}, options.FindOne().SetProjection(fields)).Decode(&data)
 	//err := m.Collection.FindOne(ctx, bson.M{"_id": course.ID}, options.FindOne().SetProjection(fields)).Decode(&data)
 
-	err := m.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(fields)).Decode(&data)
+	err := m.Collection.FindOne(ctx, filter).Decode(&data)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return apperror.ErrNoData // document might have been deleted
@@ -503,10 +1026,11 @@ func (m CourseModel) UpdateCourse(course *Course, userID string) error {
 		}
 	*/
 
-	credentials := m.CredentialsReader(userID, false)
+	// no friendship lookup: friends may see members-only courses, but not change them
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false)
 
 	// ToDO: GrantPermission für Course-Klasse erstellen
-	err = GrantPermissions(data.VisibilityCode, data.CreatedID, credentials)
+	err = authorization.GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
 	if err != nil {
 		// no wrapping needed, since function returns app errors
 		return err
@@ -530,8 +1054,11 @@ func (m CourseModel) UpdateCourse(course *Course, userID string) error {
 	course.MetaInfo.ModifiedTS = time.Now()
 	course.MetaInfo.TouchedTS = course.MetaInfo.ModifiedTS
 
+	// the record version is part of the filter, so the revision saved below is exactly the replaced version
+	filter = append(filter, bson.E{Key: "metaInfo.recVer", Value: data.MetaInfo.RecVer})
+
 	// set fields to be possibily updated
-	fields = bson.D{
+	fields := bson.D{
 		// systemfields
 		{Key: "$set", Value: bson.D{{Key: "metaInfo.modifiedTS", Value: course.MetaInfo.ModifiedTS}}},
 		{Key: "$set", Value: bson.D{{Key: "metaInfo.modifiedID", Value: course.MetaInfo.ModifiedID}}},
@@ -553,17 +1080,234 @@ func (m CourseModel) UpdateCourse(course *Course, userID string) error {
 
 	result, err := m.Collection.UpdateOne(ctx, filter, fields)
 	if err != nil {
+		if we, ok := err.(mongo.WriteException); ok && len(we.WriteErrors) > 0 && we.WriteErrors[0].Code == 11000 {
+			// share code changed to one which is used by another course of the game
+			return ErrForzaSharingCodeTaken
+		}
 		return helpers.WrapError(err, helpers.FuncName())
 	}
 
 	if result.MatchedCount == 0 {
-		return apperror.ErrNoData // document might have been deleted
+		return apperror.ErrRecordChanged // changed or deleted in the meantime
 	}
 
+	// keep the replaced version
+	m.saveRevision(&data)
+
+	m.NotifyObservers(course.ID, NotificationUpdated, credentials.UserID, credentials.LoginName)
+
 	// ToDO: überlegen - rückgsabewerte sinnvoll? (z. B. timestamp? oder die ID analog add?)
 	return nil
 }
 
+// DeleteCourse moves a course to the trash bin, from where it may be restored for a while
+// only the creator or an admin may delete a course
+func (m CourseModel) DeleteCourse(courseID string, recVer int64, userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(courseID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	// read "metadata" to check permissions and perform optimistic locking
+	fields := bson.D{
+		{Key: "_id", Value: 0},
+		{Key: "metaInfo.createdID", Value: 1},
+		{Key: "metaInfo.recVer", Value: 1},
+		{Key: "visibilityCD", Value: 1},
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$exists", Value: true}}},        // courses only
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // not yet in trash bin
+	}
+
+	data := struct {
+		MetaInfo       Header `bson:"metaInfo"`
+		VisibilityCode int32  `bson:"visibilityCD"`
+	}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err = m.Collection.FindOne(ctx, filter, options.FindOne().SetProjection(fields)).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData // document might have been deleted already
+		}
+		// pass any other error
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false)
+
+	err = authorization.GrantPermissions(data.VisibilityCode, data.MetaInfo.CreatedID, credentials)
+	if err != nil {
+		// no wrapping needed, since function returns app errors
+		return err
+	}
+
+	// must be admin or creator
+	if !(data.MetaInfo.CreatedID == credentials.UserID || credentials.RoleCode == lookups.UserRoleAdmin) {
+		return apperror.ErrDenied
+	}
+
+	// optimistic lock check
+	if data.MetaInfo.RecVer != recVer {
+		return apperror.ErrRecordChanged
+	}
+
+	// the record version is part of the filter, so a concurrent update won't be lost
+	filter = append(filter, bson.E{Key: "metaInfo.recVer", Value: recVer})
+
+	fields = bson.D{
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.deletedTS", Value: time.Now()}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.deletedID", Value: credentials.UserID}}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.deletedName", Value: credentials.LoginName}}},
+		{Key: "$inc", Value: bson.D{{Key: "metaInfo.recVer", Value: 1}}},
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.ErrRecordChanged
+	}
+
+	return nil
+}
+
+// RestoreCourse takes a course out of the trash bin (creator or admin, within the retention period)
+func (m CourseModel) RestoreCourse(courseID string, userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(courseID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false)
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$gt", Value: time.Now().Add(-TrashRetention())}}},
+	}
+	if credentials.RoleCode != lookups.UserRoleAdmin {
+		filter = append(filter, bson.E{Key: "metaInfo.createdID", Value: credentials.UserID})
+	}
+
+	fields := bson.D{
+		{Key: "$unset", Value: bson.D{
+			{Key: "metaInfo.deletedTS", Value: ""},
+			{Key: "metaInfo.deletedID", Value: ""},
+			{Key: "metaInfo.deletedName", Value: ""},
+		}},
+		{Key: "$set", Value: bson.D{{Key: "metaInfo.touchedTS", Value: time.Now()}}},
+		{Key: "$inc", Value: bson.D{{Key: "metaInfo.recVer", Value: 1}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		// not in trash bin, expired or not the user's course
+		return apperror.ErrNoData
+	}
+
+	return nil
+}
+
+// ListDeletedCourses returns the trash bin of a user (admins see every deleted course)
+func (m CourseModel) ListDeletedCourses(userID string) ([]CourseListItem, error) {
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false)
+
+	filter := bson.D{
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$exists", Value: true}}},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$gt", Value: time.Now().Add(-TrashRetention())}}},
+	}
+	if credentials.RoleCode != lookups.UserRoleAdmin {
+		filter = append(filter, bson.E{Key: "metaInfo.createdID", Value: credentials.UserID})
+	}
+
+	sort := bson.D{
+		{Key: "metaInfo.deletedTS", Value: -1},
+	}
+
+	opts := options.Find().SetLimit(50).SetSort(sort)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var courses []Course
+	err = cursor.All(ctx, &courses)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if courses == nil {
+		return nil, apperror.ErrNoData
+	}
+
+	return m.toListItems(courses), nil
+}
+
+// PurgeCourses finally removes courses which are in the trash bin longer than the retention period
+// usually called by a GO-routine that runs in a ticker
+func (m CourseModel) PurgeCourses() {
+
+	filter := bson.D{
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$exists", Value: true}}},
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$lte", Value: time.Now().Add(-TrashRetention())}}},
+	}
+
+	fields := bson.D{
+		{Key: "_id", Value: 1},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cursor, err := m.Collection.Find(ctx, filter, options.Find().SetProjection(fields))
+	if err != nil {
+		// ToDO: Log
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	var courses []Course
+	err = cursor.All(ctx, &courses)
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	for _, c := range courses {
+		_, err = m.Collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: c.ID}})
+		if err != nil {
+			fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+			continue
+		}
+		m.removeDependencies(c.ID)
+	}
+
+	if len(courses) > 0 {
+		fmt.Printf("%v: %v course(s) purged.\n", time.Now().Format(time.RFC3339), len(courses))
+	}
+}
+
 // SetRating is called by the voting model
 func (m CourseModel) SetRating(social *Social) error {
 
@@ -579,16 +1323,36 @@ func (m CourseModel) SetRating(social *Social) error {
 
 	filter := bson.D{{Key: "_id", Value: social.ProfileOID}}
 
+	// the creator is notified about the vote
+	opts := options.FindOneAndUpdate().SetProjection(bson.D{
+		{Key: "name", Value: 1},
+		{Key: "metaInfo.createdID", Value: 1},
+	})
+
+	var course Course
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
 
-	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	err := m.Collection.FindOneAndUpdate(ctx, filter, fields, opts).Decode(&course)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData // document might have been deleted
+		}
 		return helpers.WrapError(err, helpers.FuncName())
 	}
 
-	if result.MatchedCount == 0 {
-		return apperror.ErrNoData // document might have been deleted
+	// revoked votes are not notified
+	if social.Vote != VoteNeutral {
+		m.Notify(Notification{
+			UserID:      course.MetaInfo.CreatedID,
+			Type:        NotificationVote,
+			ProfileID:   social.ProfileOID,
+			ProfileType: "course",
+			ProfileName: course.Name,
+			ActorID:     social.VoterID,
+			ActorName:   social.VoterName,
+		})
 	}
 
 	return nil
@@ -596,6 +1360,96 @@ func (m CourseModel) SetRating(social *Social) error {
 
 // internal helpers (private methods)
 
+// removeDependencies cleans up data of other models that references a deleted course
+// the course itself is already gone, so errors are logged and the remaining steps are still performed
+func (m CourseModel) removeDependencies(courseOID primitive.ObjectID) {
+
+	// comments and their replies (and the votes on those)
+	commentIDs, err := m.DeleteComments(courseOID)
+	if err != nil {
+		// ToDO: log
+		fmt.Println(err)
+	}
+
+	err = m.DeleteVotes(append(commentIDs, courseOID))
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	err = m.DeleteUploads(courseOID)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	err = m.RemoveObservers(courseOID)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	err = m.RemoveNotifications(courseOID)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	// previous versions
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err = m.Revisions.DeleteMany(ctx, bson.D{{Key: "courseID", Value: courseOID}})
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+	}
+
+	// remove the course from championship line-ups (same collection)
+	filter := bson.D{{Key: "races._id", Value: courseOID}}
+	update := bson.D{
+		{Key: "$pull", Value: bson.D{
+			{Key: "races", Value: bson.D{{Key: "_id", Value: courseOID}}},
+		}},
+	}
+
+	_, err = m.Collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+	}
+}
+
+// copy data to reduced list-struct
+func (m CourseModel) toListItems(courses []Course) []CourseListItem {
+
+	var courseList []CourseListItem
+	var course CourseListItem
+
+	for _, c := range courses {
+		course.ID = c.ID
+		course.CreatedTS = primitive.ObjectID.Timestamp(c.ID)
+		course.CreatedID = c.MetaInfo.CreatedID
+		course.CreatedName = c.MetaInfo.CreatedName
+		course.Rating = c.MetaInfo.Rating
+		course.Visits = c.MetaInfo.Visits
+		course.GameCode = c.GameCode
+		course.GameText = database.GetLookupText(lookups.LookupType(lookups.LTgame), c.GameCode)
+		course.Name = c.Name
+		course.ForzaSharing = c.ForzaSharing
+		course.SeriesCode = c.SeriesCode
+		course.SeriesText = database.GetLookupText(lookups.LookupType(lookups.LTseries), c.SeriesCode)
+		course.StyleCode = c.StyleCode
+		course.StyleText = database.GetLookupText(lookups.LookupType(lookups.LTcourseStyle), c.StyleCode)
+		course.CarClasses = nil
+		if len(c.CarClasses) > 0 {
+			course.CarClasses = make([]Lookup, len(c.CarClasses))
+			for i, v := range c.CarClasses {
+				course.CarClasses[i].Value = v.Value
+				course.CarClasses[i].Text = database.GetLookupText(lookups.LookupType(lookups.LTcarClass), v.Value)
+			}
+		}
+
+		courseList = append(courseList, course)
+	}
+
+	return courseList
+}
+
 // actually that's not immutable, but ok here
 func (m CourseModel) addLookups(course *Course) *Course {
 	course.VisibilityText = database.GetLookupText(lookups.LookupType(lookups.LTvisibility), course.VisibilityCode)
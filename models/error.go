@@ -13,7 +13,12 @@ var (
 	ErrEMailAddressTaken    = errors.New("email-address is already used")
 	ErrInvalidUser          = errors.New("invalid user name or password")
 	ErrInvalidPassword      = errors.New("password does not meet rules")
+	ErrPasswordReused       = errors.New("password was used recently")
 	ErrInvalidFriend        = errors.New("could not add/remove friend")
+	ErrUserBlocked          = errors.New("user is blocked")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorCodeInvalid = errors.New("invalid two-factor code")
 )
 
 // course
@@ -22,6 +27,16 @@ var (
 	ErrForzaSharingCodeMissing = errors.New("sharing code is required")
 	ErrCourseNameMissing       = errors.New("course name is required")
 	ErrForzaSharingCodeTaken   = errors.New("forza sharing code already used")
+	ErrInvalidCursor           = errors.New("invalid paging cursor")
+	ErrForzaSharingCodeInvalid = errors.New("forza sharing code is not valid for the game")
+)
+
+// championship
+// transformed by controllers to respective Unprocessable Entity (422)
+var (
+	ErrChampionshipNameMissing  = errors.New("championship name is required")
+	ErrChampionshipRacesMissing = errors.New("championship requires at least one race")
+	ErrChampionshipRaceInvalid  = errors.New("race is not available for this championship")
 )
 
 // comment
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"os"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,20 +11,23 @@ import (
 // Header is used as an embedded type for an object's meta-info
 // no required bindings (binding:"required") since the CRUD-Operations have different meanings
 type Header struct {
-	CreatedTS    time.Time          `json:"createdTS" bson:"-"` // CreatedTS is read from Mongo's ObjectID
-	CreatedID    primitive.ObjectID `json:"createdID" bson:"createdID"`
-	CreatedName  string             `json:"createdName" bson:"createdName"`
-	ModifiedTS   time.Time          `json:"modifiedTS" bson:"modifiedTS,omitempty"` // edited if present
-	ModifiedID   primitive.ObjectID `json:"modifiedID" bson:"modifiedID,omitempty"` // maybe used to flag "edited by admin"
-	ModifiedName string             `json:"modifiedName" bson:"modifiedName,omitempty"`
-	Rating       float32            `json:"rating" bson:"rating"`         // calculated by the voting function & persisted
-	RatingSort   float32            `json:"ratingSort" bson:"ratingSort"` // calculated by the voting function & persisted (lowerBound)
-	UpVotes      int32              `json:"upVotes" bson:"upVotes"`       // votes persisted by "castVotes" for faster reading
-	DownVotes    int32              `json:"downVotes" bson:"downVotes"`
-	UserVote     int32              `json:"userVote" bson:"-"`              // returned dynamically by API
-	TouchedTS    time.Time          `json:"touchedTS" bson:"touchedTS"`     // de-norm of many sources (maybe nested or referenced)
-	RecVer       int64              `json:"recVer" bson:"recVer"`           // optimistic locking (update, delete) - starts with 1 (by .Add)
-	Visits       int64              `json:"visits" bson:"visits,omitempty"` // total amount replicated from analytics store
+	CreatedTS    time.Time           `json:"createdTS" bson:"-"` // CreatedTS is read from Mongo's ObjectID
+	CreatedID    primitive.ObjectID  `json:"createdID" bson:"createdID"`
+	CreatedName  string              `json:"createdName" bson:"createdName"`
+	ModifiedTS   time.Time           `json:"modifiedTS" bson:"modifiedTS,omitempty"` // edited if present
+	ModifiedID   primitive.ObjectID  `json:"modifiedID" bson:"modifiedID,omitempty"` // maybe used to flag "edited by admin"
+	ModifiedName string              `json:"modifiedName" bson:"modifiedName,omitempty"`
+	Rating       float32             `json:"rating" bson:"rating"`         // calculated by the voting function & persisted
+	RatingSort   float32             `json:"ratingSort" bson:"ratingSort"` // calculated by the voting function & persisted (lowerBound)
+	UpVotes      int32               `json:"upVotes" bson:"upVotes"`       // votes persisted by "castVotes" for faster reading
+	DownVotes    int32               `json:"downVotes" bson:"downVotes"`
+	UserVote     int32               `json:"userVote" bson:"-"`                              // returned dynamically by API
+	TouchedTS    time.Time           `json:"touchedTS" bson:"touchedTS"`                     // de-norm of many sources (maybe nested or referenced)
+	RecVer       int64               `json:"recVer" bson:"recVer"`                           // optimistic locking (update, delete) - starts with 1 (by .Add)
+	Visits       int64               `json:"visits" bson:"visits,omitempty"`                 // total amount replicated from analytics store
+	DeletedTS    *time.Time          `json:"deletedTS,omitempty" bson:"deletedTS,omitempty"` // moved to trash bin if present
+	DeletedID    *primitive.ObjectID `json:"deletedID,omitempty" bson:"deletedID,omitempty"`
+	DeletedName  string              `json:"deletedName,omitempty" bson:"deletedName,omitempty"`
 }
 
 // SmallHeader is used for embedded content, such as file references (arrays) or comments
@@ -34,3 +39,13 @@ type SmallHeader struct {
 	Rating      float32            `json:"rating" bson:"rating"`         // calculated & persisted (sorting, usually not shown in clients)
 	RatingSort  float32            `json:"ratingSort" bson:"ratingSort"` // calculated by the voting function & persisted (lowerBound)
 }
+
+// TrashRetention returns how long deleted items may be restored before they're purged
+func TrashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		// ToDO: Log/Panic: Invalid Config
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,369 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"forza-garage/apperror"
+	"forza-garage/events"
+	"forza-garage/helpers"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// notification types
+const (
+	// watchlist
+	NotificationUpdated   = "updated"   // an observed profile was changed
+	NotificationCommented = "commented" // someone commented on an observed profile
+	NotificationUploaded  = "uploaded"  // someone uploaded a file to an observed profile
+	// social (profile = the user's own profile or item)
+	NotificationFriendRequest  = "friendRequest"  // someone wants to be the user's friend (profile = that user)
+	NotificationFriendAccepted = "friendAccepted" // a friend request of the user was accepted (profile = the new friend)
+	NotificationFollower       = "follower"       // someone follows the user (profile = that user)
+	NotificationReply          = "reply"          // someone replied to the user's comment (profile = the commented item)
+	NotificationVote           = "vote"           // someone voted on the user's course
+	NotificationUploadApproved = "uploadApproved" // moderation decision on the user's upload
+	NotificationUploadRejected = "uploadRejected"
+)
+
+// notification paging
+const (
+	NotificationDefaultLimit = 20
+	NotificationMaxLimit     = 100
+)
+
+// Notification informs a user about an event concerning a profile (eg. an observed course)
+type Notification struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	UserID      primitive.ObjectID `json:"-" bson:"userID"` // recipient
+	Type        string             `json:"type" bson:"type"`
+	ProfileID   primitive.ObjectID `json:"profileID" bson:"profileID"`
+	ProfileType string             `json:"profileType" bson:"profileType"`
+	ProfileName string             `json:"profileName,omitempty" bson:"profileName,omitempty"` // not known to every event
+	ActorID     primitive.ObjectID `json:"actorID" bson:"actorID"`                             // who caused the event
+	ActorName   string             `json:"actorName" bson:"actorName"`
+	CreatedTS   time.Time          `json:"createdTS" bson:"-"` // extracted from OID
+	ReadTS      *time.Time         `json:"readTS,omitempty" bson:"readTS,omitempty"`
+}
+
+// NotificationList is a page of a user's notifications
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	Next          string         `json:"next,omitempty"` // cursor of the next page, empty on the last page
+}
+
+// position of the last notification of a page (newest first, hence the ID is sufficient)
+type notificationCursor struct {
+	ID primitive.ObjectID `json:"id"`
+}
+
+// NotificationModel provides the logic to the interface and access to the database
+type NotificationModel struct {
+	Collection   *mongo.Collection
+	GetObservers func(profileOID primitive.ObjectID) ([]UserRef, error)                            // injected from user model
+	Publish      func(topic string, event events.Event)                                            // live updates (server-sent events)
+	IsBlocked    func(userOID primitive.ObjectID, blockedUserOID primitive.ObjectID) (bool, error) // injected from user model
+}
+
+// NotifyObservers creates a notification for every user observing a profile (except the actor)
+// it's called after the actual change was saved, hence errors are logged only
+func (m NotificationModel) NotifyObservers(profileOID primitive.ObjectID, notificationType string, actorOID primitive.ObjectID, actorName string) {
+
+	observers, err := m.GetObservers(profileOID)
+	if err != nil {
+		// ToDO: log
+		fmt.Println(err)
+		return
+	}
+
+	var notifications []interface{}
+	for _, o := range observers {
+		if o.UserID == actorOID {
+			continue
+		}
+		// observers who've blocked the actor aren't informed (errors are treated as "not blocked")
+		if blocked, _ := m.IsBlocked(o.UserID, actorOID); blocked {
+			continue
+		}
+		notifications = append(notifications, Notification{
+			ID:          primitive.NewObjectID(),
+			UserID:      o.UserID,
+			Type:        notificationType,
+			ProfileID:   profileOID,
+			ProfileType: o.ReferenceType,
+			ProfileName: o.ReferenceName, // name at the time the profile was observed
+			ActorID:     actorOID,
+			ActorName:   actorName,
+		})
+	}
+
+	if len(notifications) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err = m.Collection.InsertMany(ctx, notifications)
+	if err != nil {
+		// ToDO: log
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	for _, n := range notifications {
+		m.publish(n.(Notification))
+	}
+}
+
+// Notify creates a notification for a single user
+// it's called after the actual action was saved, hence errors are logged only
+func (m NotificationModel) Notify(notification Notification) {
+
+	// nobody is notified about their own actions
+	if notification.UserID == notification.ActorID || notification.UserID == primitive.NilObjectID {
+		return
+	}
+
+	// nor about the actions of users they've blocked (eg. votes)
+	blocked, err := m.IsBlocked(notification.UserID, notification.ActorID)
+	if err != nil {
+		// ToDO: log
+		fmt.Println(err)
+		return
+	}
+	if blocked {
+		return
+	}
+
+	notification.ID = primitive.NewObjectID()
+	notification.ReadTS = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err = m.Collection.InsertOne(ctx, notification)
+	if err != nil {
+		// ToDO: log
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		return
+	}
+
+	m.publish(notification)
+}
+
+// pushes a saved notification to the recipient's open event streams
+func (m NotificationModel) publish(notification Notification) {
+	notification.CreatedTS = notification.ID.Timestamp()
+	m.Publish(events.UserTopic(notification.UserID.Hex()), events.Event{Name: events.EventNotification, Data: notification})
+}
+
+// ListNotifications returns a page of a user's notifications (newest first)
+// the cursor is taken from the previous page, an empty cursor returns the first page
+func (m NotificationModel) ListNotifications(userID string, cursor string, limit int, unreadOnly bool) (*NotificationList, error) {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUser
+	}
+
+	if limit <= 0 {
+		limit = NotificationDefaultLimit
+	}
+	if limit > NotificationMaxLimit {
+		limit = NotificationMaxLimit
+	}
+
+	filter := bson.D{{Key: "userID", Value: userOID}}
+
+	if unreadOnly {
+		filter = append(filter, bson.E{Key: "readTS", Value: bson.D{{Key: "$exists", Value: false}}})
+	}
+
+	if cursor != "" {
+		var last notificationCursor
+		err = helpers.DecodeCursor(cursor, &last)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		filter = append(filter, bson.E{Key: "_id", Value: bson.D{{Key: "$lt", Value: last.ID}}})
+	}
+
+	// newest first
+	sort := bson.D{{Key: "_id", Value: -1}}
+
+	// one more than requested tells if there's a next page
+	opts := options.Find().SetSort(sort).SetLimit(int64(limit + 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	res, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var notifications []Notification
+	err = res.All(ctx, &notifications)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if notifications == nil {
+		return nil, apperror.ErrNoData
+	}
+
+	list := NotificationList{}
+
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		list.Next, err = helpers.EncodeCursor(notificationCursor{ID: notifications[limit-1].ID})
+		if err != nil {
+			return nil, helpers.WrapError(err, helpers.FuncName())
+		}
+	}
+
+	for i := range notifications {
+		notifications[i].CreatedTS = notifications[i].ID.Timestamp()
+	}
+	list.Notifications = notifications
+
+	return &list, nil
+}
+
+// CountUnread returns the number of unread notifications of a user
+func (m NotificationModel) CountUnread(userID string) (int64, error) {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, ErrInvalidUser
+	}
+
+	filter := bson.D{
+		{Key: "userID", Value: userOID},
+		{Key: "readTS", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	count, err := m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return count, nil
+}
+
+// MarkRead flags a notification of the user as read
+func (m NotificationModel) MarkRead(notificationID string, userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	// other users' notifications are not found
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "userID", Value: userOID},
+	}
+
+	// reading again keeps the first timestamp
+	fields := bson.D{
+		{Key: "$min", Value: bson.D{{Key: "readTS", Value: time.Now()}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.ErrNoData
+	}
+
+	return nil
+}
+
+// MarkAllRead flags all unread notifications of the user as read
+func (m NotificationModel) MarkAllRead(userID string) error {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	filter := bson.D{
+		{Key: "userID", Value: userOID},
+		{Key: "readTS", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	fields := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "readTS", Value: time.Now()}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err = m.Collection.UpdateMany(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
+// EnsureIndexes creates the indexes required by the notification queries (called at startup)
+func (m NotificationModel) EnsureIndexes() error {
+
+	// the inbox of a user, newest first
+	inbox := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+			{Key: "_id", Value: -1},
+		},
+	}
+
+	// clean-up of deleted profiles
+	profiles := mongo.IndexModel{
+		Keys: bson.D{{Key: "profileID", Value: 1}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := m.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{inbox, profiles})
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
+// RemoveNotifications deletes the notifications concerning a profile
+// used when profiles are deleted
+func (m NotificationModel) RemoveNotifications(profileOID primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err := m.Collection.DeleteMany(ctx, bson.D{{Key: "profileID", Value: profileOID}})
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
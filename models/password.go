@@ -0,0 +1,138 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"forza-garage/helpers"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonPasswords are always rejected, more can be added by a file (PWD_BLOCKLIST)
+var commonPasswords = []string{
+	"password", "password1", "password123", "passw0rd", "12345678", "123456789", "1234567890",
+	"qwertyui", "qwertyuiop", "qwerty123", "11111111", "00000000", "iloveyou", "sunshine",
+	"princess", "football", "baseball", "welcome1", "trustno1", "letmein1", "abcd1234",
+	"forzagarage", "forzahorizon", "forzamotorsport",
+}
+
+// PasswordPolicy are the rules for new passwords
+type PasswordPolicy struct {
+	MinLength   int             // characters
+	MinClasses  int             // of lower case, upper case, digits and others
+	HistorySize int             // number of recent passwords which can't be used again (including the current one)
+	Blocklist   map[string]bool // lower case
+}
+
+// LoadPasswordPolicy reads the policy from the configuration, defaults are used for missing values
+func LoadPasswordPolicy() PasswordPolicy {
+
+	policy := PasswordPolicy{
+		MinLength:   envInt("PWD_MIN_LENGTH", 8),
+		MinClasses:  envInt("PWD_MIN_CLASSES", 2),
+		HistorySize: envInt("PWD_HISTORY", 5),
+		Blocklist:   make(map[string]bool),
+	}
+
+	for _, p := range commonPasswords {
+		policy.Blocklist[p] = true
+	}
+
+	// one password per line
+	if fileName := os.Getenv("PWD_BLOCKLIST"); fileName != "" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			// ToDO: Log/Panic: Invalid Config
+			fmt.Println(err)
+			return policy
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if p := strings.TrimSpace(scanner.Text()); p != "" {
+				policy.Blocklist[strings.ToLower(p)] = true
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			fmt.Println(err) // ToDO: log
+		}
+	}
+
+	return policy
+}
+
+// Validate checks a new password against the rules
+// the login name must not be part of the password
+func (p PasswordPolicy) Validate(password string, loginName string) error {
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return ErrInvalidPassword
+	}
+
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	if lower+upper+digit+other < p.MinClasses {
+		return ErrInvalidPassword
+	}
+
+	lowerPassword := strings.ToLower(password)
+	if p.Blocklist[lowerPassword] {
+		return ErrInvalidPassword
+	}
+
+	if loginName != "" && strings.Contains(lowerPassword, strings.ToLower(loginName)) {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
+
+// checks if a password is the current one or one of the previous (hashes) kept by the policy
+func (p PasswordPolicy) checkHistory(password string, currentHash string, history []string) error {
+
+	if p.HistorySize <= 0 {
+		return nil
+	}
+
+	// most recent last (see SetPassword)
+	hashes := []string{currentHash}
+	for i := len(history) - 1; i >= 0 && len(hashes) < p.HistorySize; i-- {
+		hashes = append(hashes, history[i])
+	}
+
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		if match, _ := helpers.CompareHash(hash, password); match {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// reads a non-negative number from the configuration
+func envInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		// ToDO: Log/Panic: Invalid Config
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,292 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"forza-garage/apperror"
+	"forza-garage/authorization"
+	"forza-garage/helpers"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CourseRevision is a previous version of a course
+// it's saved by UpdateCourse before the version is replaced
+type CourseRevision struct {
+	ID         primitive.ObjectID `json:"-" bson:"_id"`
+	CourseID   primitive.ObjectID `json:"courseID" bson:"courseID"`
+	RecVer     int64              `json:"recVer" bson:"recVer"`
+	EditedTS   time.Time          `json:"editedTS" bson:"editedTS"` // when this version was saved
+	EditedID   primitive.ObjectID `json:"editedID" bson:"editedID"` // who saved this version
+	EditedName string             `json:"editedName" bson:"editedName"`
+	Course     Course             `json:"course" bson:"course"`
+}
+
+// RevisionListItem is the reduced structure used for the version history
+type RevisionListItem struct {
+	RecVer     int64              `json:"recVer"`
+	EditedTS   time.Time          `json:"editedTS"`
+	EditedID   primitive.ObjectID `json:"editedID"`
+	EditedName string             `json:"editedName"`
+	Current    bool               `json:"current"` // the version currently published
+}
+
+// FieldDiff is a field whose value differs between two versions
+type FieldDiff struct {
+	Field string      `json:"field"` // JSON-name of the field
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// ListRevisions returns the version history of a course (most recent first)
+func (m CourseModel) ListRevisions(courseID string, userID string) ([]RevisionListItem, error) {
+
+	current, err := m.readCurrent(courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.D{
+		{Key: "course", Value: 0},
+	}
+
+	sort := bson.D{
+		{Key: "recVer", Value: -1},
+	}
+
+	opts := options.Find().SetProjection(fields).SetSort(sort).SetLimit(100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Revisions.Find(ctx, bson.D{{Key: "courseID", Value: current.ID}}, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var revisions []CourseRevision
+	err = cursor.All(ctx, &revisions)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// the current version is not stored as a revision
+	editedTS, editedID, editedName := versionEditor(current)
+	list := []RevisionListItem{{
+		RecVer:     current.MetaInfo.RecVer,
+		EditedTS:   editedTS,
+		EditedID:   editedID,
+		EditedName: editedName,
+		Current:    true,
+	}}
+
+	for _, r := range revisions {
+		list = append(list, RevisionListItem{
+			RecVer:     r.RecVer,
+			EditedTS:   r.EditedTS,
+			EditedID:   r.EditedID,
+			EditedName: r.EditedName,
+		})
+	}
+
+	return list, nil
+}
+
+// DiffRevisions compares the editable fields of two versions of a course
+func (m CourseModel) DiffRevisions(courseID string, fromRecVer int64, toRecVer int64, userID string) ([]FieldDiff, error) {
+
+	current, err := m.readCurrent(courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := m.readVersion(current, fromRecVer)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := m.readVersion(current, toRecVer)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffCourses(from, to), nil
+}
+
+// RollbackCourse publishes an earlier version of a course again
+// the rollback is an ordinary update, hence it creates a new version (and keeps the replaced one)
+func (m CourseModel) RollbackCourse(courseID string, toRecVer int64, recVer int64, userID string) error {
+
+	current, err := m.readCurrent(courseID, userID)
+	if err != nil {
+		return err
+	}
+
+	// optimistic lock check (permissions are checked again by the update)
+	if current.MetaInfo.RecVer != recVer {
+		return apperror.ErrRecordChanged
+	}
+
+	if toRecVer == recVer {
+		// nothing to do
+		return nil
+	}
+
+	target, err := m.readVersion(current, toRecVer)
+	if err != nil {
+		return err
+	}
+
+	course := *target
+	course.ID = current.ID
+	course.MetaInfo.RecVer = current.MetaInfo.RecVer
+
+	return m.UpdateCourse(&course, userID)
+}
+
+// saveRevision stores a version of a course before it's replaced
+// errors are logged only, since the update itself has already been executed
+func (m CourseModel) saveRevision(course *Course) {
+
+	editedTS, editedID, editedName := versionEditor(course)
+
+	revision := CourseRevision{
+		ID:         primitive.NewObjectID(),
+		CourseID:   course.ID,
+		RecVer:     course.MetaInfo.RecVer,
+		EditedTS:   editedTS,
+		EditedID:   editedID,
+		EditedName: editedName,
+		Course:     *course,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err := m.Revisions.InsertOne(ctx, revision)
+	if err != nil {
+		// ToDO: log
+		fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+	}
+}
+
+// readCurrent returns the published version of a course, if the user may see it
+func (m CourseModel) readCurrent(courseID string, userID string) (*Course, error) {
+
+	id, err := primitive.ObjectIDFromHex(courseID)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "courseTypeCD", Value: bson.D{{Key: "$exists", Value: true}}},        // courses only
+		{Key: "metaInfo.deletedTS", Value: bson.D{{Key: "$exists", Value: false}}}, // trash bin
+	}
+
+	var course Course
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err = m.Collection.FindOne(ctx, filter).Decode(&course)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperror.ErrNoData
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false).LookupFriendship()
+
+	err = authorization.GrantPermissions(course.VisibilityCode, course.MetaInfo.CreatedID, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return &course, nil
+}
+
+// readVersion returns the content of a given version (the current one or a revision)
+func (m CourseModel) readVersion(current *Course, recVer int64) (*Course, error) {
+
+	if recVer == current.MetaInfo.RecVer {
+		return current, nil
+	}
+
+	filter := bson.D{
+		{Key: "courseID", Value: current.ID},
+		{Key: "recVer", Value: recVer},
+	}
+
+	var revision CourseRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Revisions.FindOne(ctx, filter).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperror.ErrNoData
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return &revision.Course, nil
+}
+
+// versionEditor returns who saved a version of a course (the creator for the first version)
+func versionEditor(course *Course) (time.Time, primitive.ObjectID, string) {
+	if course.MetaInfo.ModifiedID != primitive.NilObjectID {
+		return course.MetaInfo.ModifiedTS, course.MetaInfo.ModifiedID, course.MetaInfo.ModifiedName
+	}
+	return course.ID.Timestamp(), course.MetaInfo.CreatedID, course.MetaInfo.CreatedName
+}
+
+// diffCourses compares the fields which may be changed by UpdateCourse
+func diffCourses(from *Course, to *Course) []FieldDiff {
+
+	// CarClasses: the texts are not stored, so only the codes are compared
+	fromClasses := make([]int32, len(from.CarClasses))
+	for i, v := range from.CarClasses {
+		fromClasses[i] = v.Value
+	}
+	toClasses := make([]int32, len(to.CarClasses))
+	for i, v := range to.CarClasses {
+		toClasses[i] = v.Value
+	}
+
+	fields := []FieldDiff{
+		{Field: "visibilityCode", From: from.VisibilityCode, To: to.VisibilityCode},
+		{Field: "gameCode", From: from.GameCode, To: to.GameCode},
+		{Field: "forzaSharing", From: from.ForzaSharing, To: to.ForzaSharing},
+		{Field: "name", From: from.Name, To: to.Name},
+		{Field: "seriesCode", From: from.SeriesCode, To: to.SeriesCode},
+		{Field: "styleCode", From: from.StyleCode, To: to.StyleCode},
+		{Field: "carClassCodes", From: fromClasses, To: toClasses},
+		{Field: "description", From: from.Description, To: to.Description},
+		{Field: "tags", From: nonNilTags(from.Tags), To: nonNilTags(to.Tags)},
+	}
+
+	diffs := []FieldDiff{}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.From, f.To) {
+			diffs = append(diffs, f)
+		}
+	}
+
+	return diffs
+}
+
+// missing tags are not stored (omitempty), that's the same as no tags
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
@@ -17,4 +17,8 @@ type Social = struct {
 	UpVotes    int32
 	DownVotes  int32
 	TouchedTS  time.Time // a vote updates the "touched" info, not the "modified"
+	// the vote which caused the update (used to notify the profile's creator)
+	VoterID   primitive.ObjectID
+	VoterName string
+	Vote      int32
 }
@@ -0,0 +1,384 @@
+package models
+
+import (
+	"context"
+	"forza-garage/apperror"
+	"forza-garage/helpers"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// suggestion list size
+const (
+	SuggestionDefaultLimit = 10
+	SuggestionMaxLimit     = 50
+	// number of recent votes/comments of a user taken into account
+	SuggestionProfileLimit = 100
+)
+
+// weights of the suggestion sources - mutual friends count most
+const (
+	suggestionWeightFriend    = 3
+	suggestionWeightCourse    = 2
+	suggestionWeightFollowing = 1
+)
+
+// ProfileParticipants maps users to the profiles (eg. courses) they've been active on together with someone
+type ProfileParticipants map[primitive.ObjectID][]primitive.ObjectID
+
+// UserSuggestion is a user someone may know ("people you may know")
+type UserSuggestion struct {
+	UserID           primitive.ObjectID `json:"userID"`
+	UserName         string             `json:"userName"` // user name or xbox tag (PrivacyCode)
+	MutualFriends    int                `json:"mutualFriends"`
+	SharedFollowings int                `json:"sharedFollowings"`
+	SharedCourses    int                `json:"sharedCourses"` // voted on or commented
+	Score            int                `json:"score"`
+}
+
+// GetSuggestions returns users someone may know, based on mutual friends, shared followings
+// and courses both have voted on or commented - existing friends and blocked users are excluded
+func (m UserModel) GetSuggestions(userID string, limit int) ([]UserSuggestion, error) {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUser
+	}
+
+	if limit <= 0 {
+		limit = SuggestionDefaultLimit
+	}
+	if limit > SuggestionMaxLimit {
+		limit = SuggestionMaxLimit
+	}
+
+	excluded, err := m.getSuggestionExclusions(userOID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[primitive.ObjectID]*UserSuggestion)
+	candidate := func(id primitive.ObjectID) *UserSuggestion {
+		if excluded[id] {
+			return nil
+		}
+		c, ok := candidates[id]
+		if !ok {
+			c = &UserSuggestion{UserID: id}
+			candidates[id] = c
+		}
+		return c
+	}
+
+	// friends of friends
+	mutual, err := m.getFriendsOfFriends(userOID)
+	if err != nil {
+		return nil, err
+	}
+	for id, count := range mutual {
+		if c := candidate(id); c != nil {
+			c.MutualFriends = count
+		}
+	}
+
+	// users following the same users
+	shared, err := m.getCoFollowers(userOID)
+	if err != nil {
+		return nil, err
+	}
+	for id, count := range shared {
+		if c := candidate(id); c != nil {
+			c.SharedFollowings = count
+		}
+	}
+
+	// users active on the same courses (a course voted and commented counts once)
+	voters, err := m.GetCoVoters(userOID, "course")
+	if err != nil {
+		return nil, err
+	}
+	commenters, err := m.GetCoCommenters(userOID, "course")
+	if err != nil {
+		return nil, err
+	}
+	courses := make(map[primitive.ObjectID]map[primitive.ObjectID]bool)
+	for _, participants := range []ProfileParticipants{voters, commenters} {
+		for id, profileOIDs := range participants {
+			if courses[id] == nil {
+				courses[id] = make(map[primitive.ObjectID]bool)
+			}
+			for _, p := range profileOIDs {
+				courses[id][p] = true
+			}
+		}
+	}
+	for id, profiles := range courses {
+		if c := candidate(id); c != nil {
+			c.SharedCourses = len(profiles)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, apperror.ErrNoData
+	}
+
+	suggestions := make([]UserSuggestion, 0, len(candidates))
+	for _, c := range candidates {
+		c.Score = c.MutualFriends*suggestionWeightFriend + c.SharedCourses*suggestionWeightCourse + c.SharedFollowings*suggestionWeightFollowing
+		suggestions = append(suggestions, *c)
+	}
+
+	// best first, the ID keeps the order stable
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].UserID.Hex() < suggestions[j].UserID.Hex()
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return m.addSuggestionNames(suggestions)
+}
+
+// users who shouldn't be suggested: the user, friends, blocked users (either direction) and pending friend requests
+func (m UserModel) getSuggestionExclusions(userOID primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+
+	relTypes := bson.D{{Key: "$in", Value: bson.A{"friend", "blocking", "friendRequest"}}}
+
+	filter := bson.D{
+		{Key: "relType", Value: relTypes},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "userID", Value: userOID}},
+			bson.D{{Key: "refID", Value: userOID}},
+		}},
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "userID", Value: 1},
+		{Key: "refID", Value: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Social.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var results []UserRef
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	excluded := map[primitive.ObjectID]bool{userOID: true}
+	for _, r := range results {
+		excluded[r.UserID] = true
+		excluded[r.ReferenceID] = true
+	}
+
+	return excluded, nil
+}
+
+// counts the mutual friends of the user and the friends of their friends
+func (m UserModel) getFriendsOfFriends(userOID primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+
+	friends, err := m.getReferences(userOID.Hex(), "friend")
+	if err != nil {
+		if err == apperror.ErrNoData {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	friendOIDs := make([]primitive.ObjectID, len(friends))
+	isFriend := make(map[primitive.ObjectID]bool, len(friends))
+	for i, f := range friends {
+		friendOIDs[i] = f.ReferenceID
+		isFriend[f.ReferenceID] = true
+	}
+
+	// friendships are stored once, in either direction
+	filter := bson.D{
+		{Key: "relType", Value: "friend"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "userID", Value: bson.D{{Key: "$in", Value: friendOIDs}}}},
+			bson.D{{Key: "refID", Value: bson.D{{Key: "$in", Value: friendOIDs}}}},
+		}},
+	}
+
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "userID", Value: 1},
+		{Key: "refID", Value: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Social.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var results []UserRef
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	counts := make(map[primitive.ObjectID]int)
+	for _, r := range results {
+		// the friend's side is known, the other side is the candidate
+		if isFriend[r.UserID] && !isFriend[r.ReferenceID] {
+			counts[r.ReferenceID]++
+		}
+		if isFriend[r.ReferenceID] && !isFriend[r.UserID] {
+			counts[r.UserID]++
+		}
+	}
+
+	return counts, nil
+}
+
+// counts the followings the user shares with other users
+func (m UserModel) getCoFollowers(userOID primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+
+	followings, err := m.getReferences(userOID.Hex(), "following")
+	if err != nil {
+		if err == apperror.ErrNoData {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	followingOIDs := make([]primitive.ObjectID, len(followings))
+	for i, f := range followings {
+		followingOIDs[i] = f.ReferenceID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "relType", Value: "following"},
+			{Key: "refID", Value: bson.D{{Key: "$in", Value: followingOIDs}}},
+			{Key: "userID", Value: bson.D{{Key: "$ne", Value: userOID}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$userID"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Social.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var results []struct {
+		UserID primitive.ObjectID `bson:"_id"`
+		Count  int                `bson:"count"`
+	}
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	counts := make(map[primitive.ObjectID]int, len(results))
+	for _, r := range results {
+		counts[r.UserID] = r.Count
+	}
+
+	return counts, nil
+}
+
+// adds the current names (as chosen by the privacy setting), users who don't exist anymore are removed
+func (m UserModel) addSuggestionNames(suggestions []UserSuggestion) ([]UserSuggestion, error) {
+
+	userOIDs := make([]primitive.ObjectID, len(suggestions))
+	for i, s := range suggestions {
+		userOIDs[i] = s.UserID
+	}
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: userOIDs}}}}
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "loginName", Value: 1},
+		{Key: "XBoxTag", Value: 1},
+		{Key: "privacyCD", Value: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var users []struct {
+		ID          primitive.ObjectID `bson:"_id"`
+		LoginName   string             `bson:"loginName"`
+		XBoxTag     string             `bson:"XBoxTag"`
+		PrivacyCode int32              `bson:"privacyCD"`
+	}
+	err = cursor.All(ctx, &users)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	names := make(map[primitive.ObjectID]string, len(users))
+	for _, u := range users {
+		names[u.ID] = displayName(u.LoginName, u.XBoxTag, u.PrivacyCode)
+	}
+
+	var result []UserSuggestion
+	for _, s := range suggestions {
+		name, ok := names[s.UserID]
+		if !ok {
+			continue
+		}
+		s.UserName = name
+		result = append(result, s)
+	}
+
+	if result == nil {
+		return nil, apperror.ErrNoData
+	}
+
+	return result, nil
+}
+
+// private proc to read the result of a participants aggregation ({_id: userID, profileIDs: [...]})
+func aggregateParticipants(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) (ProfileParticipants, error) {
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var results []struct {
+		UserID     primitive.ObjectID   `bson:"_id"`
+		ProfileIDs []primitive.ObjectID `bson:"profileIDs"`
+	}
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	participants := make(ProfileParticipants, len(results))
+	for _, r := range results {
+		participants[r.UserID] = r.ProfileIDs
+	}
+
+	return participants, nil
+}
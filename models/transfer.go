@@ -0,0 +1,368 @@
+package models
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forza-garage/apperror"
+	"forza-garage/database"
+	"forza-garage/helpers"
+	"forza-garage/lookups"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bulk import/export of courses (maintenance of the standard route catalogue)
+
+// supported file formats
+const (
+	TransferFormatJSON = "json"
+	TransferFormatCSV  = "csv"
+)
+
+// CourseTransfer is one row of an import or export
+// code values are represented by their (english) lookup texts, eg. "Road" or "S1"
+type CourseTransfer struct {
+	Game         string   `json:"game"`
+	Type         string   `json:"type"` // standard if empty
+	Name         string   `json:"name"`
+	ForzaSharing int32    `json:"forzaSharing"`
+	Series       string   `json:"series"`
+	Style        string   `json:"style"`
+	CarClasses   []string `json:"carClasses"`
+	Visibility   string   `json:"visibility"` // public if empty
+	Description  string   `json:"description"`
+	Tags         []string `json:"tags"`
+}
+
+// ImportResult reports the outcome of an import
+type ImportResult struct {
+	Imported int           `json:"imported"`
+	Errors   []ImportError `json:"errors,omitempty"`
+}
+
+// ImportError is the reason why a row was not imported
+type ImportError struct {
+	Row     int    `json:"row"` // starts with 1 (CSV header not counted)
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// column order of CSV files (multiple values within a column are separated by "|")
+var transferColumns = []string{"game", "type", "name", "forzaSharing", "series", "style", "carClasses", "visibility", "description", "tags"}
+
+const transferListSeparator = "|"
+
+// ReadCourseTransfers parses a JSON-array or a CSV file with a header row
+func ReadCourseTransfers(r io.Reader, format string) ([]CourseTransfer, error) {
+
+	var rows []CourseTransfer
+
+	switch format {
+	case TransferFormatJSON:
+		err := json.NewDecoder(r).Decode(&rows)
+		if err != nil {
+			return nil, err
+		}
+	case TransferFormatCSV:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, errors.New("header row missing")
+		}
+
+		// columns are identified by the header, so their order doesn't matter
+		index := make(map[string]int)
+		for i, name := range records[0] {
+			index[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		value := func(record []string, column string) string {
+			i, ok := index[strings.ToLower(column)]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		for n, record := range records[1:] {
+			row := CourseTransfer{
+				Game:        value(record, "game"),
+				Type:        value(record, "type"),
+				Name:        value(record, "name"),
+				Series:      value(record, "series"),
+				Style:       value(record, "style"),
+				CarClasses:  splitTransferList(value(record, "carClasses")),
+				Visibility:  value(record, "visibility"),
+				Description: value(record, "description"),
+				Tags:        splitTransferList(value(record, "tags")),
+			}
+			if str := value(record, "forzaSharing"); str != "" {
+				code, err := strconv.Atoi(str)
+				if err != nil {
+					return nil, fmt.Errorf("row %v: invalid forzaSharing %q", n+1, str)
+				}
+				row.ForzaSharing = int32(code)
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	return rows, nil
+}
+
+// WriteCourseTransfers writes rows in the same format as they're read by ReadCourseTransfers
+func WriteCourseTransfers(w io.Writer, format string, rows []CourseTransfer) error {
+
+	switch format {
+	case TransferFormatJSON:
+		if rows == nil {
+			rows = []CourseTransfer{}
+		}
+		return json.NewEncoder(w).Encode(rows)
+	case TransferFormatCSV:
+		cw := csv.NewWriter(w)
+		err := cw.Write(transferColumns)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			err = cw.Write([]string{
+				row.Game,
+				row.Type,
+				row.Name,
+				strconv.Itoa(int(row.ForzaSharing)),
+				row.Series,
+				row.Style,
+				strings.Join(row.CarClasses, transferListSeparator),
+				row.Visibility,
+				row.Description,
+				strings.Join(row.Tags, transferListSeparator),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// ImportCourses saves the valid rows as new courses of the (admin) user
+// invalid rows are skipped and reported, they don't stop the import
+func (m CourseModel) ImportCourses(rows []CourseTransfer, userID string) (*ImportResult, error) {
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false)
+	if credentials.RoleCode != lookups.UserRoleAdmin {
+		return nil, apperror.ErrDenied
+	}
+
+	result := ImportResult{}
+	type shareCode struct{ game, code int32 }
+	shareCodes := make(map[shareCode]int) // code -> row, duplicates within the file
+
+	for i, row := range rows {
+		n := i + 1
+
+		reject := func(message string) {
+			result.Errors = append(result.Errors, ImportError{Row: n, Name: row.Name, Message: message})
+		}
+
+		course, err := m.fromTransfer(&row)
+		if err != nil {
+			reject(err.Error())
+			continue
+		}
+
+		validated, err := m.Validate(*course)
+		if err != nil {
+			reject(err.Error())
+			continue
+		}
+
+		// share codes are unique per game
+		key := shareCode{validated.GameCode, validated.ForzaSharing}
+		if first, ok := shareCodes[key]; ok {
+			reject(fmt.Sprintf("%v (row %v)", ErrForzaSharingCodeTaken, first))
+			continue
+		}
+		shareCodes[key] = n
+
+		exists, err := m.ForzaSharingExists(validated.GameCode, validated.ForzaSharing)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			reject(ErrForzaSharingCodeTaken.Error())
+			continue
+		}
+
+		_, err = m.insertCourse(validated, userID)
+		if err != nil {
+			if err == ErrForzaSharingCodeTaken {
+				reject(err.Error())
+				continue
+			}
+			return nil, err
+		}
+
+		result.Imported++
+	}
+
+	return &result, nil
+}
+
+// ExportCourses returns all courses matching the search filters (admins only)
+// the paging parameters of the search are ignored
+func (m CourseModel) ExportCourses(searchSpecs *CourseSearchParams, userID string) ([]CourseTransfer, error) {
+
+	credentials := m.CredentialsReader(helpers.ObjectID(userID), false)
+	if credentials.RoleCode != lookups.UserRoleAdmin {
+		return nil, apperror.ErrDenied
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// the share code is looked up first, numeric names are found by the text search (see SearchCourses)
+	courses, err := m.exportQuery(ctx, searchSpecs, userID, isShareCodeTerm(searchSpecs.SearchTerm))
+	if err == nil && courses == nil && isShareCodeTerm(searchSpecs.SearchTerm) {
+		courses, err = m.exportQuery(ctx, searchSpecs, userID, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]CourseTransfer, len(courses))
+	for i, c := range courses {
+		rows[i] = toTransfer(&c)
+	}
+
+	return rows, nil
+}
+
+// reads all courses of a search (unpaged)
+func (m CourseModel) exportQuery(ctx context.Context, searchSpecs *CourseSearchParams, userID string, byShareCode bool) ([]Course, error) {
+
+	filter, sort, textSearch := m.searchQuery(searchSpecs, userID, byShareCode)
+
+	opts := options.Find().SetSort(sort)
+	if textSearch {
+		opts.SetProjection(bson.D{{Key: "score", Value: sort[0].Value}})
+	}
+
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var courses []Course
+	err = cursor.All(ctx, &courses)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return courses, nil
+}
+
+// fromTransfer resolves the lookup texts of a row
+func (m CourseModel) fromTransfer(row *CourseTransfer) (*Course, error) {
+
+	var err error
+	course := Course{
+		TypeCode:       lookups.CourseTypeStandard,
+		VisibilityCode: lookups.VisibilityAll,
+		Name:           row.Name,
+		ForzaSharing:   row.ForzaSharing,
+		Description:    strings.TrimSpace(row.Description),
+	}
+
+	lookup := func(lt int, text string) (int32, error) {
+		value, err := database.GetLookupValue(lookups.LookupType(lt), strings.TrimSpace(text))
+		if err != nil {
+			return value, fmt.Errorf("unknown %v %q", lookups.LookupType(lt), text)
+		}
+		return value, nil
+	}
+
+	if course.GameCode, err = lookup(lookups.LTgame, row.Game); err != nil {
+		return nil, err
+	}
+	if row.Type != "" {
+		if course.TypeCode, err = lookup(lookups.LTcourseType, row.Type); err != nil {
+			return nil, err
+		}
+	}
+	if course.SeriesCode, err = lookup(lookups.LTseries, row.Series); err != nil {
+		return nil, err
+	}
+	if course.StyleCode, err = lookup(lookups.LTcourseStyle, row.Style); err != nil {
+		return nil, err
+	}
+	if row.Visibility != "" {
+		if course.VisibilityCode, err = lookup(lookups.LTvisibility, row.Visibility); err != nil {
+			return nil, err
+		}
+	}
+	for _, text := range row.CarClasses {
+		value, err := lookup(lookups.LTcarClass, text)
+		if err != nil {
+			return nil, err
+		}
+		course.CarClasses = append(course.CarClasses, Lookup{Value: value})
+	}
+	for _, tag := range row.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			course.Tags = append(course.Tags, tag)
+		}
+	}
+
+	return &course, nil
+}
+
+// toTransfer converts the code values of a course into lookup texts
+func toTransfer(course *Course) CourseTransfer {
+
+	row := CourseTransfer{
+		Game:         database.GetLookupText(lookups.LookupType(lookups.LTgame), course.GameCode),
+		Type:         database.GetLookupText(lookups.LookupType(lookups.LTcourseType), course.TypeCode),
+		Name:         course.Name,
+		ForzaSharing: course.ForzaSharing,
+		Series:       database.GetLookupText(lookups.LookupType(lookups.LTseries), course.SeriesCode),
+		Style:        database.GetLookupText(lookups.LookupType(lookups.LTcourseStyle), course.StyleCode),
+		Visibility:   database.GetLookupText(lookups.LookupType(lookups.LTvisibility), course.VisibilityCode),
+		Description:  course.Description,
+		Tags:         course.Tags,
+	}
+	for _, v := range course.CarClasses {
+		row.CarClasses = append(row.CarClasses, database.GetLookupText(lookups.LookupType(lookups.LTcarClass), v.Value))
+	}
+
+	return row
+}
+
+// splits a list column of a CSV file
+func splitTransferList(str string) []string {
+
+	var values []string
+
+	for _, v := range strings.Split(str, transferListSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
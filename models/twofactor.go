@@ -0,0 +1,349 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"forza-garage/apperror"
+	"forza-garage/helpers"
+	"forza-garage/lookups"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TwoFactorIssuer is shown by the authenticator apps
+const TwoFactorIssuer = "forza-garage.net"
+
+// number of recovery codes (each can be used once instead of an app code)
+const RecoveryCodeCount = 10
+
+// characters of recovery codes (no 0/o, 1/l)
+const recoveryCodeChars = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// TwoFactor holds the TOTP secret of an account (never sent to clients)
+type TwoFactor struct {
+	Secret        string    `bson:"secret,omitempty"`
+	PendingSecret string    `bson:"pendingSecret,omitempty"` // set up, but not confirmed by a code yet
+	LastStep      int64     `bson:"lastStep"`                // time step of the last code used (replay)
+	RecoveryCodes []string  `bson:"recoveryCodes"`           // hashes, removed when used
+	EnabledTS     time.Time `bson:"enabledTS,omitempty"`
+}
+
+// TwoFactorSetup is shown to the user to add the account to an authenticator app
+type TwoFactorSetup struct {
+	Secret string `json:"secret"` // manual entry
+	URI    string `json:"uri"`    // QR code
+}
+
+// SetupTwoFactor creates a new secret which becomes active by EnableTwoFactor
+func (m UserModel) SetupTwoFactor(userID string) (*TwoFactorSetup, error) {
+
+	user, err := m.getTwoFactorData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	filter := bson.D{{Key: "_id", Value: user.ID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "twoFactor.pendingSecret", Value: secret}}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err = m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    helpers.TOTPProvisioningURI(secret, TwoFactorIssuer, user.LoginName),
+	}, nil
+}
+
+// EnableTwoFactor activates the secret of the setup, a code of the app confirms it's been added
+// the recovery codes are returned once, only their hashes are stored
+func (m UserModel) EnableTwoFactor(userID string, code string) ([]string, error) {
+
+	user, err := m.getTwoFactorData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TwoFactor == nil || user.TwoFactor.PendingSecret == "" {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	step, ok := helpers.ValidateTOTP(user.TwoFactor.PendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: user.ID},
+		{Key: "twoFactorEnabled", Value: bson.D{{Key: "$ne", Value: true}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "twoFactorEnabled", Value: true},
+		{Key: "twoFactor", Value: TwoFactor{
+			Secret:        user.TwoFactor.PendingSecret,
+			LastStep:      step,
+			RecoveryCodes: hashes,
+			EnabledTS:     time.Now(),
+		}},
+	}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns off the second step of the log-in, a code (app or recovery) is required
+func (m UserModel) DisableTwoFactor(userID string, code string) error {
+
+	user, err := m.getTwoFactorData(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	err = m.useTwoFactorCode(user, code, true)
+	if err != nil {
+		return err
+	}
+
+	return m.removeTwoFactor(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes (eg. most of them are used), a code of the app is required
+func (m UserModel) RegenerateRecoveryCodes(userID string, code string) ([]string, error) {
+
+	user, err := m.getTwoFactorData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	err = m.useTwoFactorCode(user, code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	filter := bson.D{{Key: "_id", Value: user.ID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "twoFactor.recoveryCodes", Value: hashes}}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err = m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return codes, nil
+}
+
+// VerifyTwoFactor checks the code of the second log-in step (app or recovery code)
+// each code can only be used once
+func (m UserModel) VerifyTwoFactor(userID string, code string) error {
+
+	user, err := m.getTwoFactorData(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	return m.useTwoFactorCode(user, code, true)
+}
+
+// ResetTwoFactor turns off the second step for a user who lost the app and the recovery codes (admins only)
+func (m UserModel) ResetTwoFactor(executiveUserID string, userID string) error {
+
+	credentials := m.GetCredentials(executiveUserID, false)
+	if credentials.RoleCode != lookups.UserRoleAdmin {
+		return apperror.ErrDenied
+	}
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	return m.removeTwoFactor(userOID)
+}
+
+// reads the fields required by the two-factor functions
+func (m UserModel) getTwoFactorData(userID string) (*User, error) {
+
+	var user User
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUser
+	}
+
+	opts := options.FindOne().SetProjection(bson.D{
+		{Key: "loginName", Value: 1},
+		{Key: "twoFactorEnabled", Value: 1},
+		{Key: "twoFactor", Value: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err = m.Collection.FindOne(ctx, bson.D{{Key: "_id", Value: userOID}}, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidUser
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return &user, nil
+}
+
+// accepts a code of the app (newer than the last one used) or, if allowed, a recovery code which is removed
+// the conditions are part of the update, so concurrent requests can't use the same code
+func (m UserModel) useTwoFactorCode(user *User, code string, allowRecovery bool) error {
+
+	if user.TwoFactor == nil {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	var filter, update bson.D
+
+	if step, ok := helpers.ValidateTOTP(user.TwoFactor.Secret, code, time.Now()); ok {
+		filter = bson.D{
+			{Key: "_id", Value: user.ID},
+			{Key: "twoFactor.lastStep", Value: bson.D{{Key: "$lt", Value: step}}},
+		}
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "twoFactor.lastStep", Value: step}}}}
+	} else if allowRecovery {
+		hash := hashRecoveryCode(code)
+		filter = bson.D{
+			{Key: "_id", Value: user.ID},
+			{Key: "twoFactor.recoveryCodes", Value: hash},
+		}
+		update = bson.D{{Key: "$pull", Value: bson.D{{Key: "twoFactor.recoveryCodes", Value: hash}}}}
+	} else {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+	if result.MatchedCount == 0 {
+		// used before
+		return ErrTwoFactorCodeInvalid
+	}
+
+	return nil
+}
+
+// removes the secret and the recovery codes
+func (m UserModel) removeTwoFactor(userOID primitive.ObjectID) error {
+
+	filter := bson.D{{Key: "_id", Value: userOID}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "twoFactorEnabled", Value: false}}},
+		{Key: "$unset", Value: bson.D{{Key: "twoFactor", Value: ""}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidUser
+	}
+
+	return nil
+}
+
+// creates the recovery codes (eg. "abcde-fghjk") and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+
+	b := make([]byte, 10)
+	for i := range codes {
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var sb strings.Builder
+		for j, c := range b {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(recoveryCodeChars[int(c)%len(recoveryCodeChars)])
+		}
+
+		codes[i] = sb.String()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// recovery codes are random (unlike passwords), a plain hash is sufficient
+// and allows to look them up in the update - case and separators are ignored
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
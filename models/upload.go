@@ -80,6 +80,9 @@ type UploadModel struct {
 	GetUserNameOID func(userID primitive.ObjectID) (string, error)
 	GetCredentials func(userOID primitive.ObjectID, loadFriendlist bool) *authorization.Credentials
 	GetUserVote    func(profileID string, userID string) (int32, error) // injected from vote model
+	// observers of the profile
+	NotifyObservers func(profileOID primitive.ObjectID, notificationType string, actorOID primitive.ObjectID, actorName string) // injected from notification model
+	Notify          func(notification Notification)                                                                             // injected from notification model
 }
 
 // file locations are used internally to make functions independent of moderation status
@@ -245,6 +248,8 @@ func (m UploadModel) SaveMetaData(profileID string, profileType string, uploadIn
 				return apperror.ErrNoData // document might have been deleted
 			}
 
+			m.notifyObservers(profileOID, profileType, uploadInfo)
+
 			return nil
 		}
 
@@ -275,11 +280,20 @@ func (m UploadModel) SaveMetaData(profileID string, profileType string, uploadIn
 			return helpers.WrapError(err, helpers.FuncName()) // primitive.NilObjectID.Hex() ? probly useless
 		}
 
+		m.notifyObservers(profileOID, profileType, uploadInfo)
+
 		return nil
 	}
 
 }
 
+// profile pictures can't be observed; uploads awaiting moderation are not visible yet
+func (m UploadModel) notifyObservers(profileOID primitive.ObjectID, profileType string, uploadInfo *UploadInfo) {
+	if profileType != "user" && uploadInfo.StatusCode == lookups.CommentStatusVisible {
+		m.NotifyObservers(profileOID, NotificationUploaded, uploadInfo.UploadedID, uploadInfo.UploadedName)
+	}
+}
+
 // GetMataData returns the correct URLs based on moderation status
 // to be embedded in a profile
 func (m UploadModel) GetMetaData(profileOID primitive.ObjectID, executiveUserID string) ([]FileInfo, error) {
@@ -458,6 +472,166 @@ func (m UploadModel) DeleteUpload(profileID primitive.ObjectID, fileName string,
 
 }
 
+// ModerateUpload approves or rejects a file awaiting moderation (admins only)
+// an approved file replaces the active file of its slot, a rejected one is deleted
+func (m UploadModel) ModerateUpload(profileID string, fileName string, approve bool, executiveUserID string) error {
+
+	executiveUserOID := helpers.ObjectID(executiveUserID)
+
+	cred := m.GetCredentials(executiveUserOID, false)
+	if cred == nil || cred.RoleCode != lookups.UserRoleAdmin {
+		return apperror.ErrDenied
+	}
+
+	profileOID, err := primitive.ObjectIDFromHex(profileID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	var data UploadHeader
+
+	filter := bson.D{{Key: "profileID", Value: profileOID}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err = m.Collection.FindOne(ctx, filter).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData
+		}
+		// pass any other error
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// only staged files are moderated
+	i, location, staged := m.findFile(data.Slots, fileName)
+	if location != flStage {
+		return apperror.ErrNoData
+	}
+
+	slot := "slots." + strconv.Itoa(i)
+	// the slot must still contain the file when it's updated
+	filter = append(filter, bson.E{Key: slot + ".staged.fileName", Value: fileName})
+
+	now := time.Now()
+	staged.StatusTS = now
+	staged.StatusID = &cred.UserID
+	staged.StatusName = &cred.LoginName
+
+	var fields bson.D
+	var oldFile string
+	notificationType := NotificationUploadApproved
+
+	if approve {
+		staged.StatusCode = lookups.CommentStatusVisible
+		fields = bson.D{
+			{Key: "$set", Value: bson.D{{Key: slot + ".active", Value: staged}}},
+			{Key: "$unset", Value: bson.D{{Key: slot + ".staged", Value: ""}}},
+		}
+		// the replaced file (eg. the former profile picture)
+		if data.Slots[i].Active != nil {
+			oldFile = data.Slots[i].Active.SysFileName
+		}
+	} else {
+		notificationType = NotificationUploadRejected
+		if data.Slots[i].Active != nil {
+			// the active file remains
+			fields = bson.D{
+				{Key: "$unset", Value: bson.D{{Key: slot + ".staged", Value: ""}}},
+			}
+		} else {
+			fields = bson.D{
+				{Key: "$pull", Value: bson.D{
+					{Key: "slots", Value: bson.D{
+						{Key: "staged.fileName", Value: fileName},
+					}},
+				}},
+			}
+		}
+		oldFile = fileName
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, fields)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.MatchedCount == 0 {
+		return apperror.ErrRecordChanged // moderated or deleted in the meantime
+	}
+
+	// a profile without files has no upload document (see DeleteUpload)
+	if !approve && len(data.Slots) == 1 && data.Slots[0].Active == nil {
+		_, err = m.Collection.DeleteOne(ctx, bson.D{{Key: "profileID", Value: profileOID}, {Key: "slots", Value: bson.D{{Key: "$size", Value: 0}}}})
+		if err != nil {
+			fmt.Println(helpers.WrapError(err, helpers.FuncName()))
+		}
+	}
+
+	if oldFile != "" {
+		err = os.Remove(os.Getenv("UPLOAD_TARGET") + "/" + oldFile)
+		if err != nil {
+			// ToDO: log
+			fmt.Println(err)
+		}
+	}
+
+	m.Notify(Notification{
+		UserID:      staged.UploadedID,
+		Type:        notificationType,
+		ProfileID:   profileOID,
+		ProfileType: data.ProfileType,
+		ActorID:     cred.UserID,
+		ActorName:   cred.LoginName,
+	})
+
+	// the file is visible from now on
+	if approve {
+		m.notifyObservers(profileOID, data.ProfileType, staged)
+	}
+
+	return nil
+}
+
+// DeleteUploads removes the upload metadata of a profile and all of its files
+// used when profiles are deleted (no permission checks, done by the caller)
+func (m UploadModel) DeleteUploads(profileOID primitive.ObjectID) error {
+
+	var data UploadHeader
+
+	filter := bson.D{{Key: "profileID", Value: profileOID}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Collection.FindOneAndDelete(ctx, filter).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			// profile without uploads
+			return nil
+		}
+		// pass any other error
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// staged files also reside in the target directory (see DeleteUpload)
+	for _, s := range data.Slots {
+		for _, f := range []*UploadInfo{s.Staged, s.Active} {
+			if f == nil {
+				continue
+			}
+			err = os.Remove(os.Getenv("UPLOAD_TARGET") + "/" + f.SysFileName)
+			if err != nil {
+				// ToDO: log
+				fmt.Println(err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // since the upsert operation can not be used here, this function checks if there's already a document
 // containing upload metadata for a profile
 func (m UploadModel) uploadsExists(profileID primitive.ObjectID) (bool, error) {
@@ -6,6 +6,7 @@ import (
 	"forza-garage/database"
 	"forza-garage/helpers"
 	"forza-garage/lookups"
+	"regexp"
 	"sort"
 	"time"
 
@@ -18,25 +19,81 @@ import (
 // ToDO: Sollte auch einen Header bekommen (z. B. für visits aus Repl, ModifiedTS)
 // User is the "interface" used for client communication
 type User struct {
-	ID             primitive.ObjectID `json:"id" bson:"_id"`
-	LoginName      string             `json:"loginName" bson:"loginName"` // unique
-	Password       string             `json:"password" bson:"password"`   // hash value
-	RoleCode       int32              `json:"roleCode" bson:"roleCD"`
-	RoleText       string             `json:"roleText" bson:"-"`
-	LanguageCode   int32              `json:"languageCode" bson:"languageCD" header:"Language"`
-	LanguageText   string             `json:"languageText" bson:"-"`
-	EMailAddress   string             `json:"eMail" bson:"eMail"`     // unique
-	XBoxTag        string             `json:"XBoxTag" bson:"XBoxTag"` // unique
-	PrivacyCode    int32              `json:"privacyCode" bson:"privacyCD"`
-	PrivacyText    string             `json:"privacyText" bson:"-"` // what to show to others in profile (usr-name vs xbox-tag)
-	Joined         time.Time          `json:"joinedTS" bson:"-"`
-	LastSeenTS     []time.Time        `json:"lastSeen" bson:"lastSeen,omitempty"` // limited to 5 in DB-Query (setLastSeen)
-	Friends        []UserRef          `json:"friends" bson:"-"`                   // loaded from diff. collection, at request
-	Following      []UserRef          `json:"following" bson:"-"`                 // loaded from diff. collection, at request
-	Followers      []UserRef          `json:"followers" bson:"-"`                 // loaded from diff. collection, at request
-	ProfilePicture *FileInfo          `json:"profilePicture,omitempty" bson:"-"`  // set by func
-
-	// ToDo: []LastPasswords - check for 90 days or 10 entries
+	ID               primitive.ObjectID `json:"id" bson:"_id"`
+	LoginName        string             `json:"loginName" bson:"loginName"` // unique
+	Password         string             `json:"password" bson:"password"`   // hash value
+	RoleCode         int32              `json:"roleCode" bson:"roleCD"`
+	RoleText         string             `json:"roleText" bson:"-"`
+	LanguageCode     int32              `json:"languageCode" bson:"languageCD" header:"Language"`
+	LanguageText     string             `json:"languageText" bson:"-"`
+	EMailAddress     string             `json:"eMail" bson:"eMail"`                 // unique
+	EMailVerified    bool               `json:"eMailVerified" bson:"eMailVerified"` // set by VerifyEMail
+	XBoxTag          string             `json:"XBoxTag" bson:"XBoxTag"`             // unique
+	PrivacyCode      int32              `json:"privacyCode" bson:"privacyCD"`
+	PrivacyText      string             `json:"privacyText" bson:"-"` // what to show to others in profile (usr-name vs xbox-tag)
+	Joined           time.Time          `json:"joinedTS" bson:"-"`
+	LastSeenTS       []time.Time        `json:"lastSeen" bson:"lastSeen,omitempty"`       // limited to 5 in DB-Query (setLastSeen)
+	Friends          []UserRef          `json:"friends" bson:"-"`                         // loaded from diff. collection, at request
+	Following        []UserRef          `json:"following" bson:"-"`                       // loaded from diff. collection, at request
+	Followers        []UserRef          `json:"followers" bson:"-"`                       // loaded from diff. collection, at request
+	SocialCounts     *SocialCounts      `json:"socialCounts,omitempty" bson:"-"`          // counted in diff. collection, at request
+	ProfilePicture   *FileInfo          `json:"profilePicture,omitempty" bson:"-"`        // set by func
+	TwoFactorEnabled bool               `json:"twoFactorEnabled" bson:"twoFactorEnabled"` // log-in requires a code (TOTP)
+	TwoFactor        *TwoFactor         `json:"-" bson:"twoFactor,omitempty"`             // secret and recovery codes
+	PasswordHistory  []string           `json:"-" bson:"pwdHistory,omitempty"`            // previous hashes (most recent last), see PasswordPolicy
+}
+
+// SocialCounts are the sizes of a user's lists
+type SocialCounts struct {
+	Friends   int64 `json:"friends"`
+	Following int64 `json:"following"`
+	Followers int64 `json:"followers"`
+}
+
+// UserProfile is the public view of a user (shown to other users and visitors)
+// private data such as the eMail-Address is not part of it
+type UserProfile struct {
+	ID             primitive.ObjectID `json:"id"`
+	DisplayName    string             `json:"displayName"` // user name or xbox tag (PrivacyCode)
+	Joined         time.Time          `json:"joinedTS"`
+	ProfilePicture *FileInfo          `json:"profilePicture,omitempty"` // set by func
+	Counts         ProfileCounts      `json:"counts"`
+	RecentCourses  []CourseListItem   `json:"recentCourses"` // public courses only
+}
+
+// ProfileCounts are shown in the public profile
+type ProfileCounts struct {
+	Courses int64 `json:"courses"` // public courses only
+	SocialCounts
+}
+
+// number of courses shown in the public profile
+const ProfileRecentCourses = 5
+
+// social list paging
+const (
+	SocialListDefaultLimit = 20
+	SocialListMaxLimit     = 100
+)
+
+// SocialListSpecs controls the paged lists of friends, followings and followers
+type SocialListSpecs struct {
+	Search string // prefix of the other user's name
+	Cursor string
+	Limit  int
+}
+
+// UserRefList is a page of a social list (sorted by the other user's name)
+type UserRefList struct {
+	References []UserRef `json:"references"`
+	Total      int64     `json:"total"`          // all pages (matching the search)
+	Next       string    `json:"next,omitempty"` // cursor of the next page, empty on the last page
+}
+
+// position of the last reference of a page
+type socialCursor struct {
+	Name string             `json:"n"`
+	ID   primitive.ObjectID `json:"id"`
 }
 
 // Credentials is used for programmatic control
@@ -67,7 +124,13 @@ type UserModel struct {
 	// could be a map - overkill ;-)
 	Collection        *mongo.Collection
 	Social            *mongo.Collection
-	GetProfilePicture func(profileOID primitive.ObjectID, userID string) ([]FileInfo, error) // injected from upload model
+	PasswordPolicy    PasswordPolicy
+	GetProfilePicture func(profileOID primitive.ObjectID, userID string) ([]FileInfo, error)            // injected from upload model
+	Notify            func(notification Notification)                                                   // injected from notification model
+	GetPublicCourses  func(creatorOID primitive.ObjectID, limit int64) ([]CourseListItem, int64, error) // injected from course model
+	// users active on the same profiles (suggestions)
+	GetCoVoters     func(userOID primitive.ObjectID, profileType string) (ProfileParticipants, error) // injected from vote model
+	GetCoCommenters func(userOID primitive.ObjectID, profileType string) (ProfileParticipants, error) // injected from comment model
 }
 
 // UserExists checks if a User Name is available - used in client for in-type error checking
@@ -106,14 +169,23 @@ func (m UserModel) CreateUser(user User) (string, error) {
 		return "", ErrEMailAddressTaken
 	}
 
+	err = m.PasswordPolicy.Validate(user.Password, user.LoginName)
+	if err != nil {
+		return "", err
+	}
+
 	pwdHash, err := helpers.GenerateHash(user.Password)
 	if err != nil {
 		return "", helpers.WrapError(err, helpers.FuncName())
 	}
 
 	user.ID = primitive.NewObjectID()
+	user.PasswordHistory = nil
+	user.TwoFactorEnabled = false
+	user.TwoFactor = nil
 	user.Password = pwdHash
-	user.RoleCode = lookups.UserRoleGuest
+	user.RoleCode = lookups.UserRoleGuest // until the eMail-Address is verified
+	user.EMailVerified = false
 	user.LastSeenTS = append(user.LastSeenTS, time.Now())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -156,6 +228,29 @@ func (m UserModel) GetUserByName(userName string) (*User, error) {
 	return &user, nil
 }
 
+// GetUserByEMail reads a user's login account data by the eMail-Address (eg. to send a new password)
+func (m UserModel) GetUserByEMail(eMailAddress string) (*User, error) {
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Collection.FindOne(ctx, bson.M{"eMail": eMailAddress}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidUser
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	user.Joined = primitive.ObjectID(user.ID).Timestamp()
+
+	m.addLookups(&user)
+
+	return &user, nil
+}
+
 // GetUserByID reads a user's login account data
 func (m UserModel) GetUserByID(executiveUserID string, effectiveUserID string) (*User, error) {
 
@@ -195,6 +290,9 @@ func (m UserModel) GetUserByID(executiveUserID string, effectiveUserID string) (
 		user.ProfilePicture.URL = pp[0].URL // filename only - URL is built by controller
 	}
 
+	// any error is treated as "no counts"
+	user.SocialCounts, _ = m.GetSocialCounts(effectiveUserID)
+
 	// add look-up text
 	//user.RoleText = database.GetLookupText(lookups.LookupType(lookups.LTuserRole), user.RoleCode)
 	m.addLookups(&user)
@@ -202,6 +300,69 @@ func (m UserModel) GetUserByID(executiveUserID string, effectiveUserID string) (
 	return &user, nil
 }
 
+// GetUserProfile returns the public profile of a user (effective), as seen by another user or a visitor (executive)
+func (m UserModel) GetUserProfile(executiveUserID string, effectiveUserID string) (*UserProfile, error) {
+
+	id, err := primitive.ObjectIDFromHex(effectiveUserID)
+	if err != nil {
+		return nil, apperror.ErrNoData
+	}
+
+	data := struct {
+		LoginName   string `bson:"loginName"`
+		XBoxTag     string `bson:"XBoxTag"`
+		PrivacyCode int32  `bson:"privacyCD"`
+	}{}
+
+	fields := bson.D{
+		{Key: "_id", Value: 0},
+		{Key: "loginName", Value: 1},
+		{Key: "XBoxTag", Value: 1},
+		{Key: "privacyCD", Value: 1},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(fields)).Decode(&data)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperror.ErrNoData
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	profile := UserProfile{
+		ID:     id,
+		Joined: id.Timestamp(),
+	}
+
+	profile.DisplayName = displayName(data.LoginName, data.XBoxTag, data.PrivacyCode)
+
+	pp, _ := m.GetProfilePicture(id, executiveUserID)
+	// any error is treated as "no/default" picture
+	if pp != nil {
+		profile.ProfilePicture = new(FileInfo)
+		profile.ProfilePicture.Description = pp[0].Description
+		profile.ProfilePicture.StatusCode = pp[0].StatusCode
+		profile.ProfilePicture.StatusText = pp[0].StatusText
+		profile.ProfilePicture.URL = pp[0].URL // filename only - URL is built by controller
+	}
+
+	counts, err := m.GetSocialCounts(effectiveUserID)
+	if err != nil {
+		return nil, err
+	}
+	profile.Counts.SocialCounts = *counts
+
+	profile.RecentCourses, profile.Counts.Courses, err = m.GetPublicCourses(id, ProfileRecentCourses)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
 // GetUserName returns the login name from an ID (reduced version, without profile data)
 func (m UserModel) GetUserName(ID string) (string, error) {
 
@@ -300,8 +461,18 @@ func (m UserModel) SetLastSeen(userID primitive.ObjectID) {
 }
 
 // SetPassword is used to change a User's password
+// the password must meet the policy and may not be one of the recent passwords
 func (m UserModel) SetPassword(userID primitive.ObjectID, newPassword string) error {
-	// ToDO: call PWD-Validator func
+
+	user, err := m.getPasswordData(userID)
+	if err != nil {
+		return err
+	}
+
+	err = m.checkNewPassword(user, newPassword)
+	if err != nil {
+		return err
+	}
 
 	pwdHash, err := helpers.GenerateHash(newPassword)
 	if err != nil {
@@ -311,6 +482,16 @@ func (m UserModel) SetPassword(userID primitive.ObjectID, newPassword string) er
 	filter := bson.D{{Key: "_id", Value: userID}}
 	update := bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: pwdHash}}}}
 
+	// the current one is kept as previous password (the history doesn't include the current password)
+	if m.PasswordPolicy.HistorySize > 1 && user.Password != "" {
+		update = append(update, bson.E{Key: "$push", Value: bson.D{
+			{Key: "pwdHistory", Value: bson.D{
+				{Key: "$each", Value: bson.A{user.Password}},
+				{Key: "$slice", Value: -(m.PasswordPolicy.HistorySize - 1)},
+			}},
+		}})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
 
@@ -328,6 +509,54 @@ func (m UserModel) SetPassword(userID primitive.ObjectID, newPassword string) er
 	return nil
 }
 
+// ValidatePassword checks a new password of a user without setting it (eg. before a reset token is used up)
+func (m UserModel) ValidatePassword(userID primitive.ObjectID, newPassword string) error {
+
+	user, err := m.getPasswordData(userID)
+	if err != nil {
+		return err
+	}
+
+	return m.checkNewPassword(user, newPassword)
+}
+
+// VerifyEMail marks the eMail-Address of a user as confirmed
+// guests become members, other roles are kept
+func (m UserModel) VerifyEMail(userID string) error {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	filter := bson.D{{Key: "_id", Value: userOID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "eMailVerified", Value: true}}}}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidUser
+	}
+
+	filter = bson.D{
+		{Key: "_id", Value: userOID},
+		{Key: "roleCD", Value: lookups.UserRoleGuest},
+	}
+	update = bson.D{{Key: "$set", Value: bson.D{{Key: "roleCD", Value: lookups.UserRoleMember}}}}
+
+	_, err = m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
 // GetCredentials returns account infos to control permissions and text-out (language)
 // any error is considered an anonymous user (visitor) to public items
 func (m UserModel) GetCredentials(UserID string, loadFriendlist bool) *Credentials {
@@ -403,7 +632,23 @@ func (m UserModel) GetFollowers(userID string) ([]UserRef, error) {
 	return m.getReferences(userID, "follower")
 }
 
+// ListFriends returns a page of a user's friends
+func (m UserModel) ListFriends(userID string, specs SocialListSpecs) (*UserRefList, error) {
+	return m.listReferences(userID, "friend", specs)
+}
+
+// ListFollowings returns a page of the users someone (the userID) is following
+func (m UserModel) ListFollowings(userID string, specs SocialListSpecs) (*UserRefList, error) {
+	return m.listReferences(userID, "following", specs)
+}
+
+// ListFollowers returns a page of the users who are following someone (the userID)
+func (m UserModel) ListFollowers(userID string, specs SocialListSpecs) (*UserRefList, error) {
+	return m.listReferences(userID, "follower", specs)
+}
+
 // BlockUser blocks another user's interactions
+// friendship, pending requests and followings between the users are removed
 func (m UserModel) BlockUser(userID string, blockedUserID string) error {
 	if userID == blockedUserID {
 		return ErrInvalidUser
@@ -421,8 +666,8 @@ func (m UserModel) BlockUser(userID string, blockedUserID string) error {
 	}
 
 	blockedUserInfo := m.GetCredentials(blockedUserID, false)
-	if err != nil {
-		return err
+	if blockedUserInfo.LoginName == "" {
+		return ErrInvalidUser
 	}
 
 	data := UserRef{
@@ -433,9 +678,36 @@ func (m UserModel) BlockUser(userID string, blockedUserID string) error {
 		ReferenceType: "user",
 		RelationType:  "blocking"}
 
-	// nil or wrapped error
-	return m.addReference(data)
+	// blocking twice is not an error
+	_, err = m.upsertReference(data)
+	if err != nil {
+		return err
+	}
+
+	// friends and friend requests are matched in either direction
+	for _, relationType := range []string{"friend", "friendRequest"} {
+		err = m.removeReference(UserRef{
+			UserID:       userOID,
+			ReferenceID:  blockedUserInfo.UserID,
+			RelationType: relationType})
+		if err != nil {
+			return err
+		}
+	}
+
+	// followings are directed
+	err = m.removeReference(UserRef{
+		UserID:       userOID,
+		ReferenceID:  blockedUserInfo.UserID,
+		RelationType: "following"})
+	if err != nil {
+		return err
+	}
 
+	return m.removeReference(UserRef{
+		UserID:       blockedUserInfo.UserID,
+		ReferenceID:  userOID,
+		RelationType: "following"})
 }
 
 // UnblockUser un-blocks another user's interactions
@@ -469,140 +741,785 @@ func (m UserModel) UnblockUser(userID string, blockedUserID string) error {
 
 }
 
-// AddFriend adds another user to the friendlist (receives strings from controller)
-func (m UserModel) AddFriend(userID string, friendUserID string) error {
-	// ToDO: Check if taerget has blocked
+// GetBlockedUsers lists all users blocked by someone (the userID)
+func (m UserModel) GetBlockedUsers(userID string) ([]UserRef, error) {
+	// cal private proc
 
-	if userID == friendUserID {
-		return ErrInvalidFriend
+	return m.getReferences(userID, "blocking")
+}
+
+// IsBlocked checks if a user has blocked another user
+func (m UserModel) IsBlocked(userOID primitive.ObjectID, blockedUserOID primitive.ObjectID) (bool, error) {
+
+	filter := bson.D{
+		{Key: "userID", Value: userOID},
+		{Key: "refID", Value: blockedUserOID},
+		{Key: "relType", Value: "blocking"},
 	}
 
-	// objectID required for update
-	userOID, err := primitive.ObjectIDFromHex(userID)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	count, err := m.Social.CountDocuments(ctx, filter)
 	if err != nil {
-		return ErrInvalidUser
+		return false, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	friendOID, err := primitive.ObjectIDFromHex(friendUserID)
-	if err != nil {
-		return ErrInvalidUser
+	return count > 0, nil
+}
+
+// GetBlockedIDs returns the IDs of all users blocked by a user (used to filter lists)
+func (m UserModel) GetBlockedIDs(userOID primitive.ObjectID) ([]primitive.ObjectID, error) {
+
+	filter := bson.D{
+		{Key: "userID", Value: userOID},
+		{Key: "relType", Value: "blocking"},
 	}
 
-	userName, err := m.GetUserName(userID)
+	// not limited, other than the displayed lists
+	opts := options.Find().SetProjection(bson.D{{Key: "refID", Value: 1}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Social.Find(ctx, filter, opts)
 	if err != nil {
-		return err
+		return nil, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	friendInfo := m.GetCredentials(friendUserID, false)
+	var results []UserRef
+	err = cursor.All(ctx, &results)
 	if err != nil {
-		return err
+		return nil, helpers.WrapError(err, helpers.FuncName())
 	}
 
-	// ein eintrag ist genug, da diese beziehungen nicht gerichtet (wie bspw. Vormund/Mündel) sind
-	// somit entfallen teure Transaktionen
-	data := UserRef{
-		UserID:        userOID,
-		UserName:      userName,
-		ReferenceID:   friendOID,
-		ReferenceName: friendInfo.LoginName,
-		ReferenceType: "user",
-		RelationType:  "friend"}
+	ids := make([]primitive.ObjectID, len(results))
+	for i, r := range results {
+		ids[i] = r.ReferenceID
+	}
 
-	// nil or wrapped error
-	return m.addReference(data)
+	return ids, nil
 }
 
-// RemoveFriend deletes a user from the friendlist
-func (m UserModel) RemoveFriend(userID string, friendUserID string) error {
-	return nil
+// checks if either of the users has blocked the other one
+func (m UserModel) isBlockedEither(userOID primitive.ObjectID, otherUserOID primitive.ObjectID) (bool, error) {
+
+	filter := bson.D{
+		{Key: "relType", Value: "blocking"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "userID", Value: userOID}, {Key: "refID", Value: otherUserOID}},
+			bson.D{{Key: "userID", Value: otherUserOID}, {Key: "refID", Value: userOID}},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	count, err := m.Social.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return count > 0, nil
 }
 
-// FollowUser "registers" a user to follow another user
-func (m UserModel) FollowUser(userID string, followUserID string) error {
-	// ToDO: Mehrere auf einmal unterstüzen?
+// AddFriend sends a friend request to another user (receives strings from controller)
+// the users become friends when the request is accepted - or right away, if the other user has already asked
+// returns true if the users are friends now
+func (m UserModel) AddFriend(userID string, friendUserID string) (bool, error) {
 
-	if userID == followUserID {
-		return ErrInvalidFriend
+	if userID == friendUserID {
+		return false, ErrInvalidFriend
 	}
 
 	// objectID required for update
 	userOID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return ErrInvalidUser
+		return false, ErrInvalidUser
 	}
 
-	followOID, err := primitive.ObjectIDFromHex(followUserID)
+	friendOID, err := primitive.ObjectIDFromHex(friendUserID)
 	if err != nil {
-		return ErrInvalidUser
+		return false, ErrInvalidUser
 	}
 
 	userName, err := m.GetUserName(userID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	followInfo := m.GetCredentials(followUserID, false)
+	friendInfo := m.GetCredentials(friendUserID, false)
+	if friendInfo.LoginName == "" {
+		return false, ErrInvalidUser
+	}
+
+	blocked, err := m.isBlockedEither(userOID, friendOID)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if blocked {
+		return false, ErrUserBlocked
+	}
+
+	friends, err := m.isFriend(userOID, friendOID)
+	if err != nil {
+		return false, err
+	}
+	if friends {
+		// nothing to do
+		return true, nil
+	}
+
+	// a pending request of the other user is accepted by asking back
+	err = m.AcceptFriendRequest(userID, friendUserID)
+	if err == nil {
+		return true, nil
+	}
+	if err != apperror.ErrNoData {
+		return false, err
 	}
 
-	// ein eintrag ist genug, da diese beziehungen nicht gerichtet (wie bspw. Vormund/Mündel) sind
-	// somit entfallen teure Transaktionen
 	data := UserRef{
 		UserID:        userOID,
 		UserName:      userName,
-		ReferenceID:   followOID,
-		ReferenceName: followInfo.LoginName,
+		ReferenceID:   friendOID,
+		ReferenceName: friendInfo.LoginName,
 		ReferenceType: "user",
-		RelationType:  "following"}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel() // nach 10 Sekunden abbrechen
+		RelationType:  "friendRequest"}
 
-	_, err = m.Social.InsertOne(ctx, data)
+	// asking again doesn't create another request
+	created, err := m.upsertReference(data)
 	if err != nil {
-		return helpers.WrapError(err, helpers.FuncName())
+		return false, err
 	}
 
-	return nil
+	// notify new requests only
+	if created {
+		m.Notify(Notification{
+			UserID:      friendOID,
+			Type:        NotificationFriendRequest,
+			ProfileID:   userOID,
+			ProfileType: "user",
+			ProfileName: userName,
+			ActorID:     userOID,
+			ActorName:   userName,
+		})
+	}
+
+	return false, nil
 }
 
-// private proc to write relations/referenced documents, such as friends
-func (m UserModel) addReference(userRef UserRef) error {
+// AcceptFriendRequest makes the user and the requesting user friends
+func (m UserModel) AcceptFriendRequest(userID string, requesterID string) error {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel() // nach 10 Sekunden abbrechen
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
 
-	// ID currently not needed
-	_, err := m.Social.InsertOne(ctx, userRef)
+	requesterOID, err := primitive.ObjectIDFromHex(requesterID)
 	if err != nil {
-		return helpers.WrapError(err, helpers.FuncName())
+		return apperror.ErrNoData
 	}
 
-	return nil
-}
+	// the request is directed to the user
+	filter := bson.D{
+		{Key: "userID", Value: requesterOID},
+		{Key: "refID", Value: userOID},
+		{Key: "relType", Value: "friendRequest"},
+	}
+
+	var request UserRef
 
-// private proc to delete relations/referenced documents, such as friends
-func (m UserModel) removeReference(userRef UserRef) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel() // nach 10 Sekunden abbrechen
 
-	// build unique key
+	err = m.Social.FindOneAndDelete(ctx, filter).Decode(&request)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apperror.ErrNoData
+		}
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// ein eintrag ist genug, da diese beziehungen nicht gerichtet (wie bspw. Vormund/Mündel) sind
+	// somit entfallen teure Transaktionen
+	data := UserRef{
+		UserID:        request.UserID,
+		UserName:      request.UserName,
+		ReferenceID:   request.ReferenceID,
+		ReferenceName: request.ReferenceName,
+		ReferenceType: "user",
+		RelationType:  "friend"}
+
+	err = m.addReference(data)
+	if err != nil {
+		return err
+	}
+
+	m.Notify(Notification{
+		UserID:      request.UserID,
+		Type:        NotificationFriendAccepted,
+		ProfileID:   userOID,
+		ProfileType: "user",
+		ProfileName: request.ReferenceName,
+		ActorID:     userOID,
+		ActorName:   request.ReferenceName,
+	})
+
+	return nil
+}
+
+// DeclineFriendRequest rejects a friend request directed to the user (the requester is not notified)
+func (m UserModel) DeclineFriendRequest(userID string, requesterID string) error {
+	return m.removeFriendRequest(requesterID, userID)
+}
+
+// CancelFriendRequest withdraws a friend request of the user
+func (m UserModel) CancelFriendRequest(userID string, friendUserID string) error {
+	return m.removeFriendRequest(userID, friendUserID)
+}
+
+// GetFriendRequests lists the pending friend requests directed to the user (incoming) or sent by the user
+func (m UserModel) GetFriendRequests(userID string, incoming bool) ([]UserRef, error) {
+	// cal private proc
+
+	if incoming {
+		return m.getReferences(userID, "requestIn")
+	}
+	return m.getReferences(userID, "requestOut")
+}
+
+// RemoveFriend deletes a user from the friendlist, no matter who asked for the friendship
+// pending requests between the users are withdrawn as well (not being friends is not an error)
+func (m UserModel) RemoveFriend(userID string, friendUserID string) error {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	friendOID, err := primitive.ObjectIDFromHex(friendUserID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	for _, relationType := range []string{"friend", "friendRequest"} {
+		data := UserRef{
+			UserID:       userOID,
+			ReferenceID:  friendOID,
+			RelationType: relationType}
+
+		err = m.removeReference(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FollowUser "registers" a user to follow another user
+func (m UserModel) FollowUser(userID string, followUserID string) error {
+	// ToDO: Mehrere auf einmal unterstüzen?
+
+	if userID == followUserID {
+		return ErrInvalidFriend
+	}
+
+	// objectID required for update
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	followOID, err := primitive.ObjectIDFromHex(followUserID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	userName, err := m.GetUserName(userID)
+	if err != nil {
+		return err
+	}
+
+	followInfo := m.GetCredentials(followUserID, false)
+	if followInfo.LoginName == "" {
+		return ErrInvalidUser
+	}
+
+	blocked, err := m.isBlockedEither(userOID, followOID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+
+	// ein eintrag ist genug, da diese beziehungen nicht gerichtet (wie bspw. Vormund/Mündel) sind
+	// somit entfallen teure Transaktionen
+	data := UserRef{
+		UserID:        userOID,
+		UserName:      userName,
+		ReferenceID:   followOID,
+		ReferenceName: followInfo.LoginName,
+		ReferenceType: "user",
+		RelationType:  "following"}
+
+	// following twice does not count twice
+	created, err := m.upsertReference(data)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		m.Notify(Notification{
+			UserID:      followOID,
+			Type:        NotificationFollower,
+			ProfileID:   userOID,
+			ProfileType: "user",
+			ProfileName: userName,
+			ActorID:     userOID,
+			ActorName:   userName,
+		})
+	}
+
+	return nil
+}
+
+// UnfollowUser stops following another user (not following is not an error)
+func (m UserModel) UnfollowUser(userID string, followUserID string) error {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	followOID, err := primitive.ObjectIDFromHex(followUserID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	data := UserRef{
+		UserID:       userOID,
+		ReferenceID:  followOID,
+		RelationType: "following"}
+
+	// nil or wrapped error
+	return m.removeReference(data)
+}
+
+// GetSocialCounts returns the number of friends, followings and followers of a user
+func (m UserModel) GetSocialCounts(userID string) (*SocialCounts, error) {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUser
+	}
+
+	filters := []bson.D{
+		// friendships are stored once, in either direction
+		{
+			{Key: "relType", Value: "friend"},
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "userID", Value: userOID}},
+				bson.D{{Key: "refID", Value: userOID}},
+			}},
+		},
+		{{Key: "relType", Value: "following"}, {Key: "userID", Value: userOID}},
+		{{Key: "relType", Value: "following"}, {Key: "refID", Value: userOID}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	counts := make([]int64, len(filters))
+	for i, f := range filters {
+		counts[i], err = m.Social.CountDocuments(ctx, f)
+		if err != nil {
+			return nil, helpers.WrapError(err, helpers.FuncName())
+		}
+	}
+
+	return &SocialCounts{Friends: counts[0], Following: counts[1], Followers: counts[2]}, nil
+}
+
+// AddObserver registers a user to observe a profile (eg. a course)
+// the profile is checked by the calling model, observing twice does not create a second reference
+func (m UserModel) AddObserver(userOID primitive.ObjectID, profileOID primitive.ObjectID, profileName string, profileType string) error {
+
+	userName, err := m.GetUserNameOID(userOID)
+	if err != nil {
+		return err
+	}
+
+	data := UserRef{
+		UserID:        userOID,
+		UserName:      userName,
+		ReferenceID:   profileOID,
+		ReferenceName: profileName,
+		ReferenceType: profileType,
+		RelationType:  "observing"}
+
+	_, err = m.upsertReference(data)
+
+	// nil or wrapped error
+	return err
+}
+
+// RemoveObserver stops a user from observing a profile
+func (m UserModel) RemoveObserver(userID string, profileID string) error {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	profileOID, err := primitive.ObjectIDFromHex(profileID)
+	if err != nil {
+		return apperror.ErrNoData
+	}
+
+	data := UserRef{
+		UserID:       userOID,
+		ReferenceID:  profileOID,
+		RelationType: "observing"}
+
+	// nil or wrapped error
+	return m.removeReference(data)
+}
+
+// GetObservings lists the profiles (eg. courses) a user is observing
+func (m UserModel) GetObservings(userID string) ([]UserRef, error) {
+	// cal private proc
+
+	return m.getReferences(userID, "observing")
+}
+
+// GetObservers lists the users who observe a profile
+// used to notify them about changes
+func (m UserModel) GetObservers(profileOID primitive.ObjectID) ([]UserRef, error) {
+
+	filter := bson.D{
+		{Key: "refID", Value: profileOID},
+		{Key: "relType", Value: "observing"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Social.Find(ctx, filter)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var observers []UserRef
+	err = cursor.All(ctx, &observers)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return observers, nil
+}
+
+// RemoveObservers deletes all "observing" references to a profile (eg. a course)
+// used when profiles are deleted
+func (m UserModel) RemoveObservers(profileOID primitive.ObjectID) error {
+
+	filter := bson.D{
+		{Key: "refID", Value: profileOID},
+		{Key: "relType", Value: "observing"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	_, err := m.Social.DeleteMany(ctx, filter)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
+// deletes a pending friend request
+func (m UserModel) removeFriendRequest(requesterID string, targetID string) error {
+
+	requesterOID, err := primitive.ObjectIDFromHex(requesterID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	targetOID, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return ErrInvalidUser
+	}
+
+	filter := bson.D{
+		{Key: "userID", Value: requesterOID},
+		{Key: "refID", Value: targetOID},
+		{Key: "relType", Value: "friendRequest"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Social.DeleteOne(ctx, filter)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if result.DeletedCount == 0 {
+		return apperror.ErrNoData
+	}
+
+	return nil
+}
+
+// checks if two users are friends (one entry per friendship, in either direction)
+func (m UserModel) isFriend(userOID primitive.ObjectID, friendOID primitive.ObjectID) (bool, error) {
+
+	filter := bson.D{
+		{Key: "relType", Value: "friend"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "userID", Value: userOID}, {Key: "refID", Value: friendOID}},
+			bson.D{{Key: "userID", Value: friendOID}, {Key: "refID", Value: userOID}},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	count, err := m.Social.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return count > 0, nil
+}
+
+// private proc to write relations/referenced documents, such as friends
+func (m UserModel) addReference(userRef UserRef) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	// ID currently not needed
+	_, err := m.Social.InsertOne(ctx, userRef)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
+// private proc to delete relations/referenced documents, such as friends
+// undirected relations (friends, friend requests) are matched in either direction
+func (m UserModel) removeReference(userRef UserRef) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	// build unique key
 	filter := bson.D{
 		{Key: "userID", Value: userRef.UserID},
 		{Key: "refID", Value: userRef.ReferenceID},
 		{Key: "relType", Value: userRef.RelationType},
 	}
 
-	_, err := m.Social.DeleteOne(ctx, filter)
+	if userRef.RelationType == "friend" || userRef.RelationType == "friendRequest" {
+		filter = bson.D{
+			{Key: "relType", Value: userRef.RelationType},
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "userID", Value: userRef.UserID}, {Key: "refID", Value: userRef.ReferenceID}},
+				bson.D{{Key: "userID", Value: userRef.ReferenceID}, {Key: "refID", Value: userRef.UserID}},
+			}},
+		}
+	}
+
+	// many: also removes duplicates created before the references were upserted
+	_, err := m.Social.DeleteMany(ctx, filter)
 	if err != nil {
 		return helpers.WrapError(err, helpers.FuncName())
 	}
-	// fmt.Println(res.DeletedCount)
 
 	return nil
 }
 
+// private proc to write a relation only once (upsert on its unique key)
+// returns true if the relation is new
+func (m UserModel) upsertReference(userRef UserRef) (bool, error) {
+
+	// build unique key
+	filter := bson.D{
+		{Key: "userID", Value: userRef.UserID},
+		{Key: "refID", Value: userRef.ReferenceID},
+		{Key: "relType", Value: userRef.RelationType},
+	}
+
+	// names are refreshed
+	fields := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "userName", Value: userRef.UserName}}},
+		{Key: "$set", Value: bson.D{{Key: "refName", Value: userRef.ReferenceName}}},
+		{Key: "$set", Value: bson.D{{Key: "refType", Value: userRef.ReferenceType}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	result, err := m.Social.UpdateOne(ctx, filter, fields, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return result.UpsertedCount > 0, nil
+}
+
+// private proc to page through the user relations (friend, following, follower)
+// sorted and searched by the name of the other user, which is stored on either side of the document
+func (m UserModel) listReferences(userID string, relationType string, specs SocialListSpecs) (*UserRefList, error) {
+
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrInvalidUser
+	}
+
+	limit := specs.Limit
+	if limit <= 0 {
+		limit = SocialListDefaultLimit
+	}
+	if limit > SocialListMaxLimit {
+		limit = SocialListMaxLimit
+	}
+
+	// the other user's side of the document
+	var match bson.D
+	var otherID, otherName interface{}
+
+	switch relationType {
+	case "friend":
+		match = bson.D{
+			{Key: "relType", Value: "friend"},
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "userID", Value: userOID}},
+				bson.D{{Key: "refID", Value: userOID}},
+			}},
+		}
+		isUser := bson.A{"$userID", userOID}
+		otherID = bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: isUser}}, "$refID", "$userID"}}}
+		otherName = bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: isUser}}, "$refName", "$userName"}}}
+	case "following":
+		match = bson.D{{Key: "relType", Value: "following"}, {Key: "userID", Value: userOID}}
+		otherID, otherName = "$refID", "$refName"
+	case "follower":
+		match = bson.D{{Key: "relType", Value: "following"}, {Key: "refID", Value: userOID}}
+		otherID, otherName = "$userID", "$userName"
+	default:
+		return nil, apperror.ErrNoData
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "otherID", Value: otherID},
+			{Key: "otherName", Value: otherName},
+		}}},
+	}
+
+	if specs.Search != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{
+			{Key: "otherName", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(specs.Search), Options: "i"}},
+		}}})
+	}
+
+	// the total refers to all pages, hence it's counted before the cursor is applied
+	page := mongo.Pipeline{}
+	if specs.Cursor != "" {
+		var last socialCursor
+		err = helpers.DecodeCursor(specs.Cursor, &last)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		page = append(page, bson.D{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "otherName", Value: bson.D{{Key: "$gt", Value: last.Name}}}},
+			bson.D{{Key: "otherName", Value: last.Name}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: last.ID}}}},
+		}}}}})
+	}
+	// one more than requested tells if there's a next page
+	page = append(page,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "otherName", Value: 1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit + 1}},
+	)
+
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+		{Key: "page", Value: page},
+	}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := m.Social.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var results []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Page []struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			UserID    primitive.ObjectID `bson:"userID"`
+			UserName  string             `bson:"userName"`
+			RefName   string             `bson:"refName"`
+			OtherID   primitive.ObjectID `bson:"otherID"`
+			OtherName string             `bson:"otherName"`
+		} `bson:"page"`
+	}
+
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	// check for empty result set (no error raised by aggregate)
+	if len(results) == 0 || len(results[0].Page) == 0 {
+		return nil, apperror.ErrNoData
+	}
+
+	list := UserRefList{}
+	if len(results[0].Total) > 0 {
+		list.Total = results[0].Total[0].Count
+	}
+
+	refs := results[0].Page
+	if len(refs) > limit {
+		refs = refs[:limit]
+		list.Next, err = helpers.EncodeCursor(socialCursor{Name: refs[limit-1].OtherName, ID: refs[limit-1].ID})
+		if err != nil {
+			return nil, helpers.WrapError(err, helpers.FuncName())
+		}
+	}
+
+	// same structure as getReferences (reference = the other user)
+	for _, r := range refs {
+		reference := UserRef{
+			UserID:        userOID,
+			UserName:      r.UserName,
+			ReferenceID:   r.OtherID,
+			ReferenceName: r.OtherName,
+			ReferenceType: "user",
+			RelationType:  relationType,
+		}
+		if r.UserID != userOID {
+			reference.UserName = r.RefName
+		}
+		list.References = append(list.References, reference)
+	}
+
+	return &list, nil
+}
+
 // private proc to read relations/referenced documents, such as friends
 // ToDO: Intenre funktion allenfalls mit OID statt STR-ID
 func (m UserModel) getReferences(userID string, relationType string) ([]UserRef, error) {
@@ -619,6 +1536,7 @@ func (m UserModel) getReferences(userID string, relationType string) ([]UserRef,
 		"userName": 1,
 		"refID":    1,
 		"refName":  1,
+		"refType":  1,
 	}
 
 	// actually not required for friendlist, due do post-processing (sort on slice)
@@ -626,8 +1544,8 @@ func (m UserModel) getReferences(userID string, relationType string) ([]UserRef,
 		"userName": 1,
 	}
 
-	// ToDo: Limit sinnvoll?
-	opts := options.Find().SetProjection(fields).SetLimit(20).SetSort(dbSort)
+	// not limited, the displayed lists are paged by listReferences
+	opts := options.Find().SetProjection(fields).SetSort(dbSort)
 
 	// different query depending on relation type
 	var filter bson.M
@@ -647,6 +1565,18 @@ func (m UserModel) getReferences(userID string, relationType string) ([]UserRef,
 				bson.M{"refID": userOID},
 			},
 		}
+	case "requestOut":
+		// wen habe ich angefragt?
+		filter = bson.M{
+			"relType": "friendRequest",
+			"userID":  userOID,
+		}
+	case "requestIn":
+		// wer hat mich angefragt?
+		filter = bson.M{
+			"relType": "friendRequest",
+			"refID":   userOID,
+		}
 	case "following":
 		// wem folge ich? abfrage auf db.userID = userID
 		filter = bson.M{
@@ -661,6 +1591,12 @@ func (m UserModel) getReferences(userID string, relationType string) ([]UserRef,
 		}
 	case "observing":
 		// welche rat/cmp etc. beobachte ich?
+		filter = bson.M{
+			"relType": relationType,
+			"userID":  userOID,
+		}
+		// most recent first (ObjectID)
+		opts.SetSort(bson.M{"_id": -1})
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -727,6 +1663,46 @@ func (m UserModel) getReferences(userID string, relationType string) ([]UserRef,
 		}
 	}
 
+	if relationType == "blocking" {
+		for _, r := range results {
+			reference.UserID = r.UserID
+			reference.UserName = r.UserName
+			reference.ReferenceID = r.ReferenceID
+			reference.ReferenceName = r.ReferenceName
+			reference.ReferenceType = "user"
+			reference.RelationType = relationType
+
+			references = append(references, reference)
+		}
+	}
+
+	// same structure as followings/followers (reference = the other user)
+	if relationType == "requestOut" {
+		for _, r := range results {
+			reference.UserID = r.UserID
+			reference.UserName = r.UserName
+			reference.ReferenceID = r.ReferenceID
+			reference.ReferenceName = r.ReferenceName
+			reference.ReferenceType = "user"
+			reference.RelationType = relationType
+
+			references = append(references, reference)
+		}
+	}
+
+	if relationType == "requestIn" {
+		for _, r := range results {
+			reference.UserID = r.ReferenceID
+			reference.UserName = r.ReferenceName
+			reference.ReferenceID = r.UserID
+			reference.ReferenceName = r.UserName
+			reference.ReferenceType = "user"
+			reference.RelationType = relationType
+
+			references = append(references, reference)
+		}
+	}
+
 	if relationType == "follower" {
 		for _, r := range results {
 			reference.UserID = r.ReferenceID
@@ -740,6 +1716,19 @@ func (m UserModel) getReferences(userID string, relationType string) ([]UserRef,
 		}
 	}
 
+	if relationType == "observing" {
+		for _, r := range results {
+			reference.UserID = r.UserID
+			reference.UserName = r.UserName
+			reference.ReferenceID = r.ReferenceID
+			reference.ReferenceName = r.ReferenceName
+			reference.ReferenceType = r.ReferenceType // course, championship etc.
+			reference.RelationType = relationType
+
+			references = append(references, reference)
+		}
+	}
+
 	return references, nil
 }
 
@@ -755,38 +1744,6 @@ func UserReferenced(slice []UserRef, val primitive.ObjectID) bool {
 	return false
 }
 
-// GrantPermissions enforces access rights
-// ToDo: build for every entity/class - 'user' use only here
-func GrantPermissions(itemVisibilityCode int32, itemCreatorID primitive.ObjectID, credentials *Credentials) error {
-
-	if credentials.RoleCode == lookups.UserRoleAdmin {
-		return nil
-	}
-
-	if itemCreatorID == credentials.UserID {
-		return nil
-	}
-
-	if itemVisibilityCode == lookups.VisibilityMembers && credentials.RoleCode == lookups.UserRoleGuest {
-		// get a log-in and make friends
-		return apperror.ErrGuest
-	}
-
-	// if (itemVisibilityCode == lookups.VisibilityMembers) && (UserReferenced(credentials.Friends, itemCreatorID) == false) && (itemCreatorID != credentials.UserID) {
-	if (itemVisibilityCode == lookups.VisibilityMembers) && (!UserReferenced(credentials.Friends, itemCreatorID)) && (itemCreatorID != credentials.UserID) {
-		// make friends with them
-		return apperror.ErrNotFriend
-	}
-
-	if itemVisibilityCode == lookups.VisibilityNone && (credentials.UserID != itemCreatorID) {
-		// ask them to share
-		return apperror.ErrPrivate
-	}
-
-	// all checks passed
-	return nil
-}
-
 // internal (private) implementations that are used by multiple (public) methods of the model and corresponding handlers
 func (m UserModel) userExists(userName string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -841,6 +1798,52 @@ func (m UserModel) eMailExists(emailAddress string) (bool, error) {
 
 // internal helpers
 
+// displayName is the name shown to other users - the user decides by the privacy setting
+func displayName(loginName string, xboxTag string, privacyCode int32) string {
+	switch privacyCode {
+	case lookups.PrivacyXboxTag:
+		return xboxTag
+	default:
+		return loginName
+	}
+}
+
+// reads the fields required to check a new password
+func (m UserModel) getPasswordData(userID primitive.ObjectID) (*User, error) {
+
+	var user User
+
+	opts := options.FindOne().SetProjection(bson.D{
+		{Key: "loginName", Value: 1},
+		{Key: "password", Value: 1},
+		{Key: "pwdHistory", Value: 1},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	err := m.Collection.FindOne(ctx, bson.D{{Key: "_id", Value: userID}}, opts).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidUser
+		}
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return &user, nil
+}
+
+// checks a new password against the policy and the recent passwords of the user
+func (m UserModel) checkNewPassword(user *User, newPassword string) error {
+
+	err := m.PasswordPolicy.Validate(newPassword, user.LoginName)
+	if err != nil {
+		return err
+	}
+
+	return m.PasswordPolicy.checkHistory(newPassword, user.Password, user.PasswordHistory)
+}
+
 // this is used as the error handler of GetCredentials
 // any error of that function will be threated as an anonymous user, receiving the default credentials
 func (m UserModel) setDefaultProfile(credentials *Credentials) {
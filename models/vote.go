@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"forza-garage/apperror"
+	"forza-garage/events"
 	"forza-garage/helpers"
 	"math"
 	"time"
@@ -42,6 +43,13 @@ type ProfileVotes struct {
 	UserVote  int32 `json:"userVote"` // vote action of the requested user (read from token)
 }
 
+// VoteCounts is pushed to the clients showing a profile when its votes change
+type VoteCounts struct {
+	ProfileID primitive.ObjectID `json:"profileID"`
+	UpVotes   int32              `json:"upVotes"`
+	DownVotes int32              `json:"downVotes"`
+}
+
 // UserVote represents a user's vote actions to a profile
 // usually used as a slice type for lists
 type UserVote struct {
@@ -55,6 +63,7 @@ type VoteModel struct {
 	// Gewisse Informationen kommen vom User-Model, die werden hier referenziert
 	// somit muss das nicht der Controller machen
 	GetUserNameOID func(ID primitive.ObjectID) (string, error)
+	Publish        func(topic string, event events.Event) // live updates (server-sent events)
 }
 
 // CastVotes is used to vote for/against something (a profile, eg. Course/Championship)
@@ -68,9 +77,11 @@ func (v VoteModel) CastVote(
 
 	// Keine Prüfung, ob das ObjectID gültig ist. (dann braucht's alle COllections :-/)
 
+	var usr string
+
 	// 1. save or delete vote
 	if vote.Vote != VoteNeutral {
-		usr, err := v.GetUserNameOID(vote.UserID)
+		usr, err = v.GetUserNameOID(vote.UserID)
 		if err != nil {
 			return nil, ErrInvalidUser
 		}
@@ -158,10 +169,19 @@ func (v VoteModel) CastVote(
 		UpVotes:    up,
 		DownVotes:  down,
 		TouchedTS:  time.Now(),
+		VoterID:    vote.UserID,
+		VoterName:  usr,
+		Vote:       vote.Vote,
 	}
 
 	SetRating(social)
 
+	// update the vote counts shown by other clients
+	v.Publish(events.ProfileTopic(vote.ProfileID.Hex()), events.Event{
+		Name: events.EventVotes,
+		Data: VoteCounts{ProfileID: vote.ProfileID, UpVotes: up, DownVotes: down},
+	})
+
 	profileVotes = new(ProfileVotes)
 	profileVotes.DownVotes = down
 	profileVotes.UpVotes = up
@@ -251,6 +271,83 @@ func (v VoteModel) GetUserVotes(domain string, userID string) ([]UserVote, error
 	return votes, nil
 }
 
+// GetCoVoters returns the users who voted on the same profiles as the given user (used for suggestions)
+// only the user's most recent votes are taken into account
+func (v VoteModel) GetCoVoters(userOID primitive.ObjectID, profileType string) (ProfileParticipants, error) {
+
+	filter := bson.D{
+		{Key: "userID", Value: userOID},
+		{Key: "profileType", Value: profileType},
+	}
+
+	opts := options.Find().
+		SetProjection(bson.D{{Key: "profileID", Value: 1}}).
+		SetSort(bson.D{{Key: "voteTS", Value: -1}}).
+		SetLimit(SuggestionProfileLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	cursor, err := v.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	var votes []UserVote
+	err = cursor.All(ctx, &votes)
+	if err != nil {
+		return nil, helpers.WrapError(err, helpers.FuncName())
+	}
+
+	if len(votes) == 0 {
+		return ProfileParticipants{}, nil
+	}
+
+	profileOIDs := make([]primitive.ObjectID, len(votes))
+	for i, vote := range votes {
+		profileOIDs[i] = vote.ProfileID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "profileID", Value: bson.D{{Key: "$in", Value: profileOIDs}}},
+			{Key: "userID", Value: bson.D{{Key: "$ne", Value: userOID}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$userID"},
+			{Key: "profileIDs", Value: bson.D{{Key: "$addToSet", Value: "$profileID"}}},
+		}}},
+	}
+
+	return aggregateParticipants(ctx, v.Collection, pipeline)
+}
+
+// DeleteVotes removes all votes cast for the given profiles
+// used when profiles are deleted, so no orphaned votes remain
+func (v VoteModel) DeleteVotes(profileOIDs []primitive.ObjectID) error {
+
+	if len(profileOIDs) == 0 {
+		return nil
+	}
+
+	filter := bson.D{
+		{Key: "profileID", Value: bson.D{
+			{Key: "$in", Value: profileOIDs},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // nach 10 Sekunden abbrechen
+
+	// not interessted in actual result
+	_, err := v.Collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return helpers.WrapError(err, helpers.FuncName())
+	}
+
+	return nil
+}
+
 // GetVotes returns the up and down votes as well as the vote of the user
 // zur Zeit unbenutzt (gelesen über parent's meta); evtl. mal für stats-page
 /*
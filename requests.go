@@ -24,22 +24,55 @@ func handleRequests() {
 
 	// auth-related
 	router.POST("/login", controllers.Login)
+	router.POST("/login/2fa", controllers.LoginTwoFactor)                            // second step (two-factor authentication)
 	router.POST("/logout", authentication.TokenAuthMiddleware(), controllers.Logout) // DELETE in Vorlage (umstritten)
 	router.POST("/refresh", controllers.Refresh)                                     // nicht prüfen, ob das at noch valide ist (keine Middleware)
 	router.POST("/register", controllers.Register)
 
 	router.POST("/user/exists", controllers.UserExists)
 	router.POST("/email/exists", controllers.EMailExists)
+	router.POST("/email/verify", controllers.VerifyEMail)
+	router.POST("/email/verify/resend", authentication.TokenAuthMiddleware(), controllers.ResendVerification)
+	router.POST("/password/forgot", controllers.ForgotPassword)
+	router.POST("/password/reset", controllers.ResetPassword)
 
 	// user-mgmt
-	router.GET("/users/:id", authentication.TokenAuthMiddleware(), controllers.GetUser)
+	router.GET("/users/:id", controllers.GetUser) // public profile, token optional
+	router.GET("/user", authentication.TokenAuthMiddleware(), controllers.GetAccount)
 	router.POST("/user/changePass", authentication.TokenAuthMiddleware(), controllers.ChangePassword)
 	router.POST("/user/verifyPass", authentication.TokenAuthMiddleware(), controllers.VerifyPassword)
 	router.POST("/user/uploadAvatar", authentication.TokenAuthMiddleware(), controllers.UploadProfilePicture)
 
+	// two-factor authentication (TOTP)
+	router.POST("/user/2fa/setup", authentication.TokenAuthMiddleware(), controllers.SetupTwoFactor)
+	router.POST("/user/2fa/enable", authentication.TokenAuthMiddleware(), controllers.EnableTwoFactor)
+	router.POST("/user/2fa/disable", authentication.TokenAuthMiddleware(), controllers.DisableTwoFactor)
+	router.POST("/user/2fa/recoveryCodes", authentication.TokenAuthMiddleware(), controllers.RegenerateRecoveryCodes)
+	router.DELETE("/users/:id/2fa", authentication.TokenAuthMiddleware(), controllers.ResetTwoFactor) // admins only
+
+	// devices the user is logged in with
+	router.GET("/user/sessions", authentication.TokenAuthMiddleware(), controllers.ListSessions)
+	router.DELETE("/user/sessions/:id", authentication.TokenAuthMiddleware(), controllers.RevokeSession)
+
 	// nicht öffentlich, kein aufruf für andere als der aktuelle user vorgesehen (daher kein param)
+	router.GET("/user/blocked", authentication.TokenAuthMiddleware(), controllers.GetBlockedUsers)
 	router.POST("/user/blocked", authentication.TokenAuthMiddleware(), controllers.BlockUser)
 	router.DELETE("/user/blocked", authentication.TokenAuthMiddleware(), controllers.UnblockUser)
+	router.GET("/user/suggestions", authentication.TokenAuthMiddleware(), controllers.GetSuggestions)
+
+	// watchlist (courses)
+	router.GET("/user/observings", authentication.TokenAuthMiddleware(), controllers.GetObservings)
+	router.POST("/user/observings", authentication.TokenAuthMiddleware(), controllers.ObserveCourse)
+	router.DELETE("/user/observings", authentication.TokenAuthMiddleware(), controllers.UnobserveCourse)
+
+	// notifications
+	router.GET("/user/notifications", authentication.TokenAuthMiddleware(), controllers.ListNotifications)
+	router.GET("/user/notifications/unread", authentication.TokenAuthMiddleware(), controllers.CountUnreadNotifications)
+	router.POST("/user/notifications/read", authentication.TokenAuthMiddleware(), controllers.MarkNotificationRead)
+	router.POST("/user/notifications/readAll", authentication.TokenAuthMiddleware(), controllers.MarkAllNotificationsRead)
+
+	// live updates (server-sent events)
+	router.GET("/events", authentication.TokenAuthMiddleware(), controllers.StreamEvents)
 
 	router.GET("/user/votes", authentication.TokenAuthMiddleware(), controllers.GetUserVotes) // nur noch für (eigenes) profil als übersicht
 	// ToDo: /user/comments
@@ -48,9 +81,15 @@ func handleRequests() {
 	router.GET("/users/:id/friends", authentication.TokenAuthMiddleware(), controllers.GetFriends)
 	router.POST("/users/:id/friends", authentication.TokenAuthMiddleware(), controllers.AddFriend)
 	router.DELETE("/users/:id/friends", authentication.TokenAuthMiddleware(), controllers.RemoveFriend) // ToDo: anpassn {id}
+	// friend requests (POST /users/:id/friends sends one)
+	router.GET("/user/friendRequests", authentication.TokenAuthMiddleware(), controllers.GetFriendRequests)
+	router.POST("/user/friendRequests/accept", authentication.TokenAuthMiddleware(), controllers.AcceptFriendRequest)
+	router.POST("/user/friendRequests/decline", authentication.TokenAuthMiddleware(), controllers.DeclineFriendRequest)
+	router.POST("/user/friendRequests/cancel", authentication.TokenAuthMiddleware(), controllers.CancelFriendRequest)
 
 	router.GET("/users/:id/followings", authentication.TokenAuthMiddleware(), controllers.GetFollowings)
 	router.POST("/users/:id/followings", authentication.TokenAuthMiddleware(), controllers.FollowUser) // ToDo: Vs Verb "follow"
+	router.DELETE("/users/:id/followings", authentication.TokenAuthMiddleware(), controllers.UnfollowUser)
 
 	router.GET("/users/:id/followers", authentication.TokenAuthMiddleware(), controllers.GetFollowers)
 
@@ -69,9 +108,12 @@ func handleRequests() {
 
 	// commenting
 	router.POST("/comment", authentication.TokenAuthMiddleware(), controllers.AddComment) // easier handling for client
+	router.DELETE("/comments/:id", authentication.TokenAuthMiddleware(), controllers.DeleteComment)
+	router.POST("/comments/:id/restore", authentication.TokenAuthMiddleware(), controllers.RestoreComment)
 
 	// uploading
 	router.POST("/upload", authentication.TokenAuthMiddleware(), controllers.UploadFile)
+	router.POST("/upload/moderate", authentication.TokenAuthMiddleware(), controllers.ModerateFile) // admins only
 
 	// course
 	// GET hat keinen BODY (Go/Gin & Postman unterstützen das zwar, Angular nicht) - deshalb Parameter
@@ -80,9 +122,21 @@ func handleRequests() {
 	router.GET("/courses/member", authentication.TokenAuthMiddleware(), controllers.ListCoursesMember)
 	router.GET("/courses/public/:id", controllers.GetCoursePublic)
 	router.GET("/courses/member/:id", authentication.TokenAuthMiddleware(), controllers.GetCourseMember)
+	router.GET("/courses/by-share-code/:code", controllers.GetCourseByShareCode) // token optional
 	router.POST("/courses", authentication.TokenAuthMiddleware(), controllers.AddCourse)
 	router.PUT("/courses/:id", authentication.TokenAuthMiddleware(), controllers.UpdateCourse)
-	// ToDO: Delete
+	router.DELETE("/courses/member/:id", authentication.TokenAuthMiddleware(), controllers.DeleteCourse) // same prefix as the uploads (router)
+	// trash bin
+	router.GET("/courses/trash", authentication.TokenAuthMiddleware(), controllers.ListDeletedCourses)
+	router.POST("/courses/:id/restore", authentication.TokenAuthMiddleware(), controllers.RestoreCourse)
+	// derived courses
+	router.POST("/courses/:id/fork", authentication.TokenAuthMiddleware(), controllers.ForkCourse)
+	router.GET("/courses/public/:id/forks", controllers.ListForksPublic)
+	router.GET("/courses/member/:id/forks", authentication.TokenAuthMiddleware(), controllers.ListForksMember)
+	// version history
+	router.GET("/courses/member/:id/revisions", authentication.TokenAuthMiddleware(), controllers.ListRevisions)
+	router.GET("/courses/member/:id/revisions/diff", authentication.TokenAuthMiddleware(), controllers.DiffRevisions)
+	router.POST("/courses/:id/rollback", authentication.TokenAuthMiddleware(), controllers.RollbackCourse)
 	// statistics
 	router.GET("/courses/public/:id/visits", controllers.GetCourseVisits) // visits since last 7 days "hot"
 	// commenting - generic handlers for all profile types
@@ -93,9 +147,27 @@ func handleRequests() {
 	router.GET("/courses/member/:id/uploads", authentication.TokenAuthMiddleware(), controllers.DownloadFilesMember)
 	router.DELETE("/courses/member/:id/uploads/:fid", authentication.TokenAuthMiddleware(), controllers.DeleteFile)
 
+	// championship
+	router.GET("/championships/public", controllers.ListChampionshipsPublic)
+	router.GET("/championships/member", authentication.TokenAuthMiddleware(), controllers.ListChampionshipsMember)
+	router.GET("/championships/public/:id", controllers.GetChampionshipPublic)
+	router.GET("/championships/member/:id", authentication.TokenAuthMiddleware(), controllers.GetChampionshipMember)
+	router.POST("/championships", authentication.TokenAuthMiddleware(), controllers.AddChampionship)
+	router.PUT("/championships/:id", authentication.TokenAuthMiddleware(), controllers.UpdateChampionship)
+	// commenting & uploads - generic handlers for all profile types
+	router.GET("/championships/public/:id/comments", controllers.ListCommentsPublic)
+	router.GET("/championships/member/:id/comments", authentication.TokenAuthMiddleware(), controllers.ListCommentsMember)
+	router.GET("/championships/public/:id/uploads", controllers.DownloadFilesPublic)
+	router.GET("/championships/member/:id/uploads", authentication.TokenAuthMiddleware(), controllers.DownloadFilesMember)
+	router.DELETE("/championships/member/:id/uploads/:fid", authentication.TokenAuthMiddleware(), controllers.DeleteFile)
+
 	// logics
 	router.POST("/course/exists", authentication.TokenAuthMiddleware(), controllers.ExistsForzaShare) // protected to prevent sniffs ;-)
 
+	// maintenance (admins only) - own prefix, "/courses/:id" is already used for POST
+	router.POST("/admin/courses/import", authentication.TokenAuthMiddleware(), controllers.ImportCourses)
+	router.GET("/admin/courses/export", authentication.TokenAuthMiddleware(), controllers.ExportCourses)
+
 	switch os.Getenv("APP_ENV") {
 	case "DEV":
 		router.Run(":" + os.Getenv("API_PORT"))